@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// abortTimeout bounds cancel call made after interrupt, as the run is ending anyway
+const abortTimeout = 5 * time.Second
+
+// cancelMethodFor returns method stopping server-side work of method: --cancel-method,
+// or the one mapped to method in cancel_methods section of config file
+func (s *session) cancelMethodFor(method string) string {
+	if s.cancelMethod != "" {
+		return s.cancelMethod
+	}
+	return s.cancelMethods[method]
+}
+
+// abortRemote asks daemon to stop method after call was interrupted by signal, with --abort-on-interrupt.
+// Cancel method is called without params, its failure is only reported
+func (s *session) abortRemote(method string) {
+	if !s.abortOnInterrupt || !interrupted() {
+		return
+	}
+	cancelMethod := s.cancelMethodFor(method)
+	if cancelMethod == "" {
+		if s.verbose > 0 {
+			fmt.Fprintf(stderr, "* no cancel method for %s, daemon keeps running it\n", method)
+		}
+		return
+	}
+	// errors of the interrupted call keep its id
+	defer func(id int) { s.lastID = id }(s.lastID)
+	fmt.Fprintf(stderr, "Interrupted, calling %s to stop %s\n", cancelMethod, method)
+	// shutdown context is already cancelled
+	ctx, cancel := context.WithTimeout(withRecord(context.Background(), s.recorder), abortTimeout)
+	defer cancel()
+	request := s.newRequest(cancelMethod)
+	start := time.Now()
+	result, err := s.client.SendContext(ctx, request)
+	s.logCall(request, time.Since(start), result, err)
+	switch {
+	case err != nil:
+		fmt.Fprintf(stderr, "Warning: %s: %s\n", cancelMethod, s.callError(err))
+	case result.Error != nil:
+		fmt.Fprintf(stderr, "Warning: %s failed: %s\n", cancelMethod, result.Error.Message)
+	}
+}
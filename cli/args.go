@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// keywordArg matches key=value arguments. Value must not be empty or only "=", so that base64
+// values with padding like SGVsbG8= stay positional
+var keywordArg = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=(.*[^=].*)$`)
+
+// parseValue decodes arg as JSON if possible, otherwise returns it as string
+func parseValue(arg string) interface{} {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(arg)))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return arg
+	}
+	return value
+}
+
+// encodeValue encodes value read from stdin or file with --encode-params encoding.
+// Without encoding value is used as text, without trailing newline
+func encodeValue(data []byte, encoding string) (string, error) {
+	switch encoding {
+	case "":
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString(data), nil
+	case "hex":
+		return hex.EncodeToString(data), nil
+	default:
+		return "", fmt.Errorf("unknown encoding %q, expected base64 or hex", encoding)
+	}
+}
+
+// parseArgs converts command line arguments to RPC params.
+// Positional arguments produce a list, key=value arguments produce an object.
+// Positional values containing "=" can be passed as JSON strings, e.g. '"a=b"', and empty named
+// values as key='""'.
+// A single "-" value is read from stdin, if it is not nil, and "@path" value is read from file,
+// "\@" escapes literal "@". Values read from stdin or files are encoded with encoding if set
+func parseArgs(args []string, raw bool, encoding string, stdin io.Reader) (interface{}, error) {
+	if _, err := encodeValue(nil, encoding); err != nil {
+		return nil, err
+	}
+	positional := []interface{}{}
+	named := map[string]interface{}{}
+	stdinUsed := false
+	for _, arg := range args {
+		decode := !raw
+		value := arg
+		match := keywordArg.FindStringSubmatch(arg)
+		if match != nil {
+			value = match[2]
+		}
+		var data []byte
+		loaded := true
+		switch {
+		case value == "-":
+			if stdin == nil {
+				return nil, errors.New("reading argument from stdin is not supported here")
+			}
+			if stdinUsed {
+				return nil, errors.New("only one argument can be read from stdin")
+			}
+			stdinUsed = true
+			var err error
+			if data, err = ioutil.ReadAll(stdin); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(value, `\@`):
+			value, loaded = value[1:], false
+		case strings.HasPrefix(value, "@") && len(value) > 1:
+			var err error
+			if data, err = ioutil.ReadFile(value[1:]); err != nil {
+				return nil, err
+			}
+		default:
+			loaded = false
+		}
+		if loaded {
+			var err error
+			if value, err = encodeValue(data, encoding); err != nil {
+				return nil, err
+			}
+			// encoded values are always sent as strings
+			decode = decode && encoding == ""
+		}
+		var param interface{} = value
+		if decode {
+			param = parseValue(value)
+		}
+		if match != nil {
+			named[match[1]] = param
+		} else {
+			positional = append(positional, param)
+		}
+	}
+	if len(named) > 0 {
+		if len(positional) > 0 {
+			return nil, errors.New("positional and named arguments can't be mixed")
+		}
+		return named, nil
+	}
+	return positional, nil
+}
+
+// parseParams decodes JSON array or object used verbatim as RPC params.
+// Value starting with "@" is a path of file to read params from, see readParamsFile
+func parseParams(value string) (interface{}, error) {
+	var params interface{}
+	if strings.HasPrefix(value, "@") {
+		var err error
+		if params, err = readParamsFile(value[1:]); err != nil {
+			return nil, err
+		}
+	} else {
+		decoder := json.NewDecoder(strings.NewReader(value))
+		decoder.UseNumber()
+		if err := decoder.Decode(&params); err != nil {
+			return nil, fmt.Errorf("parsing --params: %v", err)
+		}
+	}
+	switch params.(type) {
+	case []interface{}, map[string]interface{}:
+		return params, nil
+	default:
+		return nil, errors.New("--params must be JSON array or object")
+	}
+}
+
+// readParamsFile decodes params file, format is detected by extension:
+// .yaml and .yml files are YAML, .toml files are TOML, other files are JSON
+func readParamsFile(path string) (interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var params interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err = yaml.Unmarshal(data, &params); err == nil {
+			params, err = jsonValue(params)
+		}
+	case ".toml":
+		table := map[string]interface{}{}
+		if _, err = toml.Decode(string(data), &table); err == nil {
+			params = table
+		}
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&params)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing params file %s: %v", path, err)
+	}
+	return params, nil
+}
+
+// commandParams returns RPC params from --params value or --params-file if set, otherwise from arguments
+func commandParams(args []string, params, paramsFile string, raw bool, encoding string, stdin io.Reader) (interface{}, error) {
+	if paramsFile != "" {
+		if params != "" {
+			return nil, errors.New("--params and --params-file can't be combined")
+		}
+		params = "@" + paramsFile
+	}
+	if params == "" {
+		return parseArgs(args, raw, encoding, stdin)
+	}
+	if len(args) > 0 {
+		return nil, errors.New("arguments can't be combined with --params")
+	}
+	return parseParams(params)
+}
+
+// splitList splits comma-separated values of repeated flag into one list
+func splitList(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				result = append(result, item)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want interface{}
+	}{
+		{nil, []interface{}{}},
+		{[]string{"addr", "1", "true"}, []interface{}{"addr", json.Number("1"), true}},
+		{[]string{"address=addr", "amount=0.5"}, map[string]interface{}{"address": "addr", "amount": json.Number("0.5")}},
+		{[]string{"label=a=b"}, map[string]interface{}{"label": "a=b"}},
+		{[]string{`label=""`}, map[string]interface{}{"label": ""}},
+		{[]string{`"a=b"`}, []interface{}{"a=b"}},
+		// base64 values with padding are positional
+		{[]string{"addr", "SGVsbG8="}, []interface{}{"addr", "SGVsbG8="}},
+		{[]string{"SGVsbG8h=="}, []interface{}{"SGVsbG8h=="}},
+		{[]string{"abc="}, []interface{}{"abc="}},
+		{[]string{`\@name`}, []interface{}{"@name"}},
+	}
+	for _, tt := range tests {
+		got, err := parseArgs(tt.args, false, "", nil)
+		if err != nil {
+			t.Errorf("parseArgs(%q) error: %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseArgs(%q) = %#v, want %#v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestParseArgsRaw(t *testing.T) {
+	got, err := parseArgs([]string{"1", "true"}, true, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"1", "true"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseArgs() with raw = %#v, want %#v", got, want)
+	}
+}
+
+func TestParseArgsStdin(t *testing.T) {
+	got, err := parseArgs([]string{"-"}, false, "hex", strings.NewReader("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"6869"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseArgs() = %#v, want %#v", got, want)
+	}
+	if _, err := parseArgs([]string{"-", "-"}, false, "", strings.NewReader("hi")); err == nil {
+		t.Error("parseArgs() read stdin twice")
+	}
+	if _, err := parseArgs([]string{"-"}, false, "", nil); err == nil {
+		t.Error("parseArgs() read missing stdin")
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	tests := [][]string{
+		{"addr", "amount=1"},
+		{"@/nonexistent/file"},
+	}
+	for _, args := range tests {
+		if got, err := parseArgs(args, false, "", nil); err == nil {
+			t.Errorf("parseArgs(%q) = %v, want error", args, got)
+		}
+	}
+	if _, err := parseArgs(nil, false, "base32", nil); err == nil {
+		t.Error("parseArgs() accepted unknown encoding")
+	}
+}
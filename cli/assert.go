@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/urfave/cli"
+)
+
+// assertExpr matches --assert expressions like balance.confirmed==0.5
+var assertExpr = regexp.MustCompile(`^(.+?)(==|!=|>|<)(.*)$`)
+
+// assertion is a check of result field at path against value
+type assertion struct {
+	expr  string
+	path  string
+	op    string
+	value string
+}
+
+// parseAssertions parses --assert expressions
+func parseAssertions(exprs []string) ([]assertion, error) {
+	assertions := make([]assertion, len(exprs))
+	for i, expr := range exprs {
+		match := assertExpr.FindStringSubmatch(expr)
+		if match == nil {
+			return nil, fmt.Errorf("invalid assertion %q, expected path==value, path!=value, path>value or path<value", expr)
+		}
+		assertions[i] = assertion{expr: expr, path: match[1], op: match[2], value: match[3]}
+	}
+	return assertions, nil
+}
+
+// check reports why result doesn't satisfy assertion, or empty string if it does.
+// Values are compared as numbers if both are numeric, as text otherwise
+func (a assertion) check(result interface{}) string {
+	field, err := selectPath(result, a.path)
+	if err != nil {
+		return err.Error()
+	}
+	text := filterText(field)
+	var cmp int
+	number, errField := strconv.ParseFloat(text, 64)
+	value, errValue := strconv.ParseFloat(a.value, 64)
+	switch {
+	case errField == nil && errValue == nil:
+		cmp = compareFloats(number, value)
+	case text < a.value:
+		cmp = -1
+	case text > a.value:
+		cmp = 1
+	}
+	ok := map[string]bool{"==": cmp == 0, "!=": cmp != 0, ">": cmp > 0, "<": cmp < 0}[a.op]
+	if ok {
+		return ""
+	}
+	return "got " + text
+}
+
+// compareFloats returns -1, 0 or 1 if a is less than, equal to or greater than b
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// checkAssertions checks result against --assert expressions, listing failed ones
+func (s *session) checkAssertions(result interface{}) error {
+	var failures []string
+	for _, a := range s.assertions {
+		if reason := a.check(result); reason != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", a.expr, reason))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	if errorJSON {
+		reportError(errorInfo{Type: "validation", Message: "assertions failed", Data: failures})
+		return cli.NewExitError("", exitAssertionFailed)
+	}
+	for _, failure := range failures {
+		fmt.Fprintln(stderr, "-", failure)
+	}
+	return fail(fmt.Sprintf("%d of %d assertions failed", len(failures), len(s.assertions)), exitAssertionFailed)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseAssertions(t *testing.T) {
+	assertions, err := parseAssertions([]string{"balance.confirmed==0.5", "label!=", "height>10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []assertion{
+		{expr: "balance.confirmed==0.5", path: "balance.confirmed", op: "==", value: "0.5"},
+		{expr: "label!=", path: "label", op: "!=", value: ""},
+		{expr: "height>10", path: "height", op: ">", value: "10"},
+	}
+	for i := range want {
+		if assertions[i] != want[i] {
+			t.Errorf("parseAssertions()[%d] = %+v, want %+v", i, assertions[i], want[i])
+		}
+	}
+	for _, expr := range []string{"confirmed", "==1"} {
+		if _, err := parseAssertions([]string{expr}); err == nil {
+			t.Errorf("parseAssertions(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestAssertionCheck(t *testing.T) {
+	result := map[string]interface{}{
+		"confirmed": "1.5",
+		"height":    json.Number("1000"),
+		"label":     "coffee",
+		"synced":    true,
+	}
+	tests := []struct {
+		expr string
+		ok   bool
+	}{
+		{"confirmed==1.5", true},
+		// numbers compare by value, not text
+		{"confirmed==1.50", true},
+		{"confirmed==2", false},
+		{"confirmed<2", true},
+		{"height>999", true},
+		{"height<1000", false},
+		{"label==coffee", true},
+		{"label!=coffee", false},
+		{"synced==true", true},
+		{"missing==1", false},
+	}
+	for _, tt := range tests {
+		assertions, err := parseAssertions([]string{tt.expr})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reason := assertions[0].check(result); (reason == "") != tt.ok {
+			t.Errorf("check(%q) = %q, want ok %v", tt.expr, reason, tt.ok)
+		}
+	}
+}
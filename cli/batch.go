@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+	"github.com/MrNaif2018/jsonrpc"
+	"github.com/urfave/cli"
+)
+
+// batchRequest is a single call from batch file. Coin, wallet and credentials default to global ones.
+// Credentials name credential set from config file, instead of user and password
+type batchRequest struct {
+	Coin        string      `json:"coin"`
+	Wallet      string      `json:"wallet"`
+	User        string      `json:"user"`
+	Password    string      `json:"password"`
+	Credentials string      `json:"credentials"`
+	Method      string      `json:"method"`
+	Params      interface{} `json:"params"`
+}
+
+// loadBatch reads batch requests from JSON file
+func loadBatch(path string) ([]batchRequest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var requests []batchRequest
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&requests); err != nil {
+		return nil, fmt.Errorf("parsing batch file %s: %v", path, err)
+	}
+	for i, request := range requests {
+		if request.Method == "" {
+			return nil, fmt.Errorf("parsing batch file %s: request %d has no method", path, i)
+		}
+	}
+	return requests, nil
+}
+
+// batchParams returns params of request to pass to session calls
+func batchParams(request batchRequest) []interface{} {
+	if request.Params == nil {
+		return nil
+	}
+	return []interface{}{request.Params}
+}
+
+// batchGroups returns indexes of entries grouped by client their session calls with,
+// in order of first entry of each group. Entries without session are left out
+func batchGroups(sessions []*session) [][]int {
+	var groups [][]int
+	positions := map[*bitcart.Client]int{}
+	for i, es := range sessions {
+		if es == nil {
+			continue
+		}
+		position, ok := positions[es.client]
+		if !ok {
+			position = len(groups)
+			positions[es.client] = position
+			groups = append(groups, nil)
+		}
+		groups[position] = append(groups[position], i)
+	}
+	return groups
+}
+
+// sendBatch sends entries at indexes, which share a client, as one JSON-RPC batch array.
+// Responses are in order of indexes, nil for entries daemon didn't answer
+func sendBatch(sessions []*session, requests []batchRequest, indexes []int) ([]*jsonrpc.RPCResponse, error) {
+	batch := make([]*jsonrpc.RPCRequest, len(indexes))
+	for j, i := range indexes {
+		// request id matches position in batch file
+		bs := *sessions[i]
+		bs.nextID = i
+		batch[j] = bs.newRequest(requests[i].Method, batchParams(requests[i])...)
+	}
+	s := sessions[indexes[0]]
+	ctx, cancel := s.callContext()
+	defer cancel()
+	start := time.Now()
+	responses, err := s.client.SendBatchContext(ctx, batch)
+	duration := time.Since(start)
+	if err != nil {
+		for j, request := range batch {
+			sessions[indexes[j]].logCall(request, duration, nil, err)
+		}
+		return nil, err
+	}
+	for j, response := range responses {
+		if response != nil {
+			sessions[indexes[j]].logCall(batch[j], duration, response, nil)
+		}
+	}
+	return responses, nil
+}
+
+// runBatch executes batch requests and prints results in the same order. Entries sharing a client are sent
+// as one JSON-RPC batch array. If daemon rejects the batch, its entries are called separately, up to
+// concurrency at once. Other failures of the batch fail all its entries, as daemon may have run it.
+// Entries with coin, which may be an alias, are sent with a session made by newCoinSession,
+// shared by all entries of that coin
+func (s *session) runBatch(path string, newCoinSession func(coin string) (*session, error)) error {
+	requests, err := loadBatch(path)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	results := make([]map[string]interface{}, len(requests))
+	oks := make([]bool, len(requests))
+	// sessions are created up front, so calls don't race on them.
+	// they are keyed by coin name as given in batch file
+	sessions := map[string]*session{"": s}
+	sessionErrors := map[string]error{}
+	entrySessions := make([]*session, len(requests))
+	for i, request := range requests {
+		name := request.Coin
+		if _, ok := sessions[name]; !ok && sessionErrors[name] == nil {
+			if cs, err := newCoinSession(name); err != nil {
+				sessionErrors[name] = err
+			} else {
+				// aliases of the same coin share its session
+				for _, other := range sessions {
+					if other.coin == cs.coin {
+						cs = other
+						break
+					}
+				}
+				sessions[name] = cs
+			}
+		}
+		if err := sessionErrors[name]; err != nil {
+			results[i] = map[string]interface{}{"error": map[string]interface{}{"message": err.Error(), "id": i}}
+			continue
+		}
+		es := *sessions[name]
+		if request.Wallet != "" {
+			es.wallet = request.Wallet
+		}
+		cs, err := es.withCredentials(request.Credentials, request.User, request.Password)
+		if err != nil {
+			results[i] = map[string]interface{}{"error": map[string]interface{}{"message": err.Error(), "id": i}}
+			continue
+		}
+		entrySessions[i] = cs
+		if requests[i].Method, requests[i].Params, err = cs.prepareCall(request.Method, request.Params, askStdin); err != nil {
+			return err
+		}
+	}
+	answered := make([]bool, len(requests))
+	if !s.dryRun && !s.mock {
+		for _, indexes := range batchGroups(entrySessions) {
+			if len(indexes) < 2 {
+				continue
+			}
+			responses, err := sendBatch(entrySessions, requests, indexes)
+			if err != nil && bitcart.IsBatchRejected(err) {
+				if s.verbose > 0 {
+					fmt.Fprintln(stderr, "* batch not accepted, sending calls separately:", err)
+				}
+				continue
+			}
+			for j, i := range indexes {
+				bs := *entrySessions[i]
+				bs.lastID = i
+				answered[i] = true
+				// daemon may have run the batch, so its calls are never sent again
+				switch {
+				case err != nil:
+					results[i], oks[i] = bs.entry(requests[i].Method, nil, err)
+				case responses[j] == nil:
+					results[i], oks[i] = bs.entry(requests[i].Method, nil, errors.New("daemon didn't answer this request of batch"))
+				case responses[j].Error != nil && bs.unlockWallet(requests[i].Method, responses[j].Error):
+					// calls failing because wallet is locked are retried separately once it is unlocked
+					answered[i] = false
+				default:
+					results[i], oks[i] = bs.entry(requests[i].Method, responses[j], nil)
+				}
+			}
+		}
+	}
+	skipped := parallel(len(requests), s.concurrency, s.stopOnError, func(i int) bool {
+		if entrySessions[i] == nil {
+			return false
+		}
+		if answered[i] {
+			return oks[i]
+		}
+		// request id matches position in batch file
+		bs := *entrySessions[i]
+		bs.nextID = i
+		bs.recorder = &responseRecord{}
+		if bs.dryRun {
+			results[i], oks[i] = bs.request(requests[i].Method, batchParams(requests[i])...), true
+			return true
+		}
+		result, err := bs.do(requests[i].Method, batchParams(requests[i])...)
+		results[i], oks[i] = bs.entry(requests[i].Method, result, err)
+		return oks[i]
+	})
+	for i := range results {
+		if skipped[i] && !answered[i] {
+			results[i] = skippedEntry
+		}
+	}
+	if err := s.output.write(results); err != nil {
+		return err
+	}
+	for _, ok := range oks {
+		if !ok {
+			return cli.NewExitError("", exitRPCError)
+		}
+	}
+	return nil
+}
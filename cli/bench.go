@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// percentile returns nearest-rank percentile p of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// benchmark makes the call n times, up to concurrency at once, and prints latency statistics to stderr.
+// Results are not printed, --verbose shows responses
+func (s *session) benchmark(n int, method string, params interface{}) error {
+	durations := make([]time.Duration, n)
+	oks := make([]bool, n)
+	start := time.Now()
+	parallel(n, s.concurrency, false, func(i int) bool {
+		bs := *s
+		bs.nextID = s.nextID + i
+		result, err := bs.do(method, params)
+		durations[i], oks[i] = bs.lastDuration, err == nil && result.Error == nil
+		return oks[i]
+	})
+	total := time.Since(start)
+	failed := 0
+	for _, ok := range oks {
+		if !ok {
+			failed++
+		}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	var sum time.Duration
+	for _, d := range durations {
+		sum += d
+	}
+	ms := func(d time.Duration) string {
+		return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+	}
+	fmt.Fprintf(stderr, "%d calls of %s, %d failed, in %v (%.1f calls/s, concurrency %d)\n",
+		n, method, failed, total.Round(time.Millisecond), float64(n)/total.Seconds(), s.concurrency)
+	fmt.Fprintf(stderr, "min %s\tavg %s\tp50 %s\tp95 %s\tmax %s\n",
+		ms(durations[0]), ms(sum/time.Duration(n)), ms(percentile(durations, 50)), ms(percentile(durations, 95)), ms(durations[n-1]))
+	if failed > 0 {
+		return cli.NewExitError("", exitRPCError)
+	}
+	return nil
+}
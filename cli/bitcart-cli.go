@@ -1,26 +1,93 @@
 package main
 
 import (
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/MrNaif2018/jsonrpc"
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
 	"github.com/urfave/cli"
 )
 
-func main() {
-	COINS := map[string]string{
-		"btc":  "http://localhost:5000",
-		"ltc":  "http://localhost:5001",
-		"gzro": "http://localhost:5002",
+// stderr receives diagnostic messages, it is discarded in quiet mode
+var stderr io.Writer = os.Stderr
+
+// process exit codes
+const (
+	exitFailure        = 1
+	exitRPCError       = 2
+	exitInvalidRequest = 3
+	exitMethodNotFound = 4
+	exitInvalidParams  = 5
+	exitInternalError  = 6
+	exitParseError     = 7
+	// result is empty and --fail-on-empty is set
+	exitEmptyResult = 8
+	// result doesn't match --validate schema
+	exitInvalidResult = 9
+	// --assert check failed
+	exitAssertionFailed = 10
+	// --ndjson stream broke off, printed lines are complete but some are missing
+	exitIncompleteStream = 11
+	// conventional code of process stopped by Ctrl-C
+	exitInterrupted = 130
+)
+
+// noMethodHelp is printed when flags are given without method
+const noMethodHelp = `Error: no method specified
+
+Examples:
+   bitcart-cli --coin btc getbalance
+   bitcart-cli --wallet xpub... history
+   bitcart-cli methods
+
+Run bitcart-cli --help to see all options
+`
+
+// rpcExitCodes maps well-known JSON-RPC error codes to process exit codes
+var rpcExitCodes = map[int]int{
+	-32600: exitInvalidRequest,
+	-32601: exitMethodNotFound,
+	-32602: exitInvalidParams,
+	-32603: exitInternalError,
+	-32700: exitParseError,
+}
+
+// rpcExitCode returns process exit code for JSON-RPC error code
+func rpcExitCode(code int) int {
+	if exitCode, ok := rpcExitCodes[code]; ok {
+		return exitCode
 	}
+	return exitRPCError
+}
+
+// rootContext returns context of the app, holding global flags
+func rootContext(c *cli.Context) *cli.Context {
+	for c.Parent() != nil {
+		c = c.Parent()
+	}
+	return c
+}
+
+func main() {
+	// registry resolves coin URLs, COINS holds the resolved ones after app.Before
+	registry := bitcart.NewRegistry()
+	COINS := registry.Coins()
 	app := cli.NewApp()
 	app.Name = "Bitcart CLI"
 	app.Version = "1.0.0"
 	app.HideHelp = true
+	app.EnableBashCompletion = true
+	// -v is taken by --verbose
+	cli.VersionFlag = cli.BoolFlag{
+		Name:  "version",
+		Usage: "print the version",
+	}
 	app.Usage = "Call RPC methods from console"
 	app.UsageText = "bitcart-cli method [args]"
 	app.Flags = []cli.Flag{
@@ -28,18 +95,68 @@ func main() {
 			Name:  "help, h",
 			Usage: "show help",
 		},
+		cli.StringFlag{
+			Name:   "user-agent",
+			Usage:  "send `VALUE` as User-Agent header, --header User-Agent takes precedence",
+			Value:  "bitcart-cli/" + app.Version,
+			EnvVar: "BITCART_USER_AGENT",
+		},
+		cli.StringFlag{
+			Name:   "config",
+			Usage:  "specify config file path, ${VAR} and ${VAR:-default} in its values are read from environment",
+			Value:  defaultConfigPath(),
+			EnvVar: "BITCART_CONFIG",
+		},
+		cli.StringFlag{
+			Name:   "profile",
+			Usage:  "apply coins and timeout of profile `NAME` from profiles section of config file",
+			EnvVar: "BITCART_PROFILE",
+		},
+		cli.StringFlag{
+			Name:   "coin-file",
+			Usage:  "read coin names and daemon URLs from JSON or YAML `FILE` mapping names to URLs, overridden by config file",
+			EnvVar: "BITCART_COIN_FILE",
+		},
+		// BITCART_WALLET is resolved in newSession, so --no-wallet and --wallet-path conflict only with the flag
 		cli.StringFlag{
 			Name:     "wallet, w",
-			Usage:    "specify wallet",
+			Usage:    "specify wallet, overrides BITCART_WALLET and coin wallet from config file",
 			Required: false,
-			EnvVar:   "BITCART_WALLET",
+		},
+		cli.StringFlag{
+			Name:  "wallet-path",
+			Usage: "specify wallet by file `PATH`, sent as absolute path with ~ expanded, instead of --wallet name",
+		},
+		cli.BoolFlag{
+			Name:  "no-wallet",
+			Usage: "send no wallet, for methods like getinfo, help, version, validateaddress or get_transaction which don't need one",
+		},
+		cli.StringSliceFlag{
+			Name:  "wallets",
+			Usage: "run method against each of comma-separated wallets, can be repeated. wallet@name uses credentials name from config file",
 		},
 		cli.StringFlag{
 			Name:   "coin, c",
-			Usage:  "specify coin to use",
+			Usage:  "specify coin to use, \"all\" runs method on every coin",
 			Value:  "btc",
 			EnvVar: "BITCART_COIN",
 		},
+		cli.StringFlag{
+			Name:   "url, U",
+			Usage:  "specify daemon URL (overrides coin setting), unix:///path/to.sock:/ for Unix socket",
+			EnvVar: "BITCART_DAEMON_URL",
+		},
+		cli.StringFlag{
+			Name:   "host",
+			Usage:  "rewrite host of all coin URLs, keeping their ports",
+			Value:  "localhost",
+			EnvVar: "BITCART_HOST",
+		},
+		cli.StringFlag{
+			Name:   "port",
+			Usage:  "rewrite port of selected coin URL",
+			EnvVar: "BITCART_PORT",
+		},
 		cli.StringFlag{
 			Name:   "user, u",
 			Usage:  "specify daemon user",
@@ -52,47 +169,910 @@ func main() {
 			Value:  "electrumz",
 			EnvVar: "BITCART_PASSWORD",
 		},
+		cli.StringFlag{
+			Name:   "password-file",
+			Usage:  "read daemon password from first line of file",
+			EnvVar: "BITCART_PASSWORD_FILE",
+		},
+		cli.StringFlag{
+			Name:   "token",
+			Usage:  "specify bearer token to use instead of user and password",
+			EnvVar: "BITCART_TOKEN",
+		},
+		cli.StringFlag{
+			Name:   "token-file",
+			Usage:  "read bearer token from first line of file",
+			EnvVar: "BITCART_TOKEN_FILE",
+		},
+		cli.StringFlag{
+			Name:   "hmac-secret",
+			Usage:  "sign request bodies with HMAC under shared `SECRET`, for gateways checking signatures",
+			EnvVar: "BITCART_HMAC_SECRET",
+		},
+		cli.StringFlag{
+			Name:  "hmac-algorithm",
+			Usage: "hash `ALGORITHM` of --hmac-secret signatures: sha1, sha256 or sha512",
+			Value: "sha256",
+		},
+		cli.StringFlag{
+			Name:  "hmac-header",
+			Usage: "send hex --hmac-secret signature in header `NAME`",
+			Value: defaultSignatureHeader,
+		},
+		cli.BoolFlag{
+			Name:  "abort-on-interrupt",
+			Usage: "when call is interrupted, call method stopping it on daemon, from cancel_methods of config file or --cancel-method",
+		},
+		cli.StringFlag{
+			Name:  "cancel-method",
+			Usage: "call `METHOD` to stop interrupted call with --abort-on-interrupt, overrides cancel_methods of config file",
+		},
+		cli.StringFlag{
+			Name:   "unlock-password",
+			Usage:  "when call fails because wallet is locked, unlock it with `PASSWORD` and retry once, overrides unlock_passwords of config file",
+			EnvVar: "BITCART_UNLOCK_PASSWORD",
+		},
+		cli.StringFlag{
+			Name:  "unlock-method",
+			Usage: "call `METHOD` with wallet password to unlock locked wallet",
+			Value: defaultUnlockMethod,
+		},
+		cli.IntFlag{
+			Name:   "timeout",
+			Usage:  "specify request timeout in seconds, 0 disables it",
+			Value:  30,
+			EnvVar: "BITCART_TIMEOUT",
+		},
+		cli.IntFlag{
+			Name:  "call-timeout",
+			Usage: "cancel each call, including its retries, after `N` seconds without closing client connections",
+		},
+		cli.DurationFlag{
+			Name:  "deadline",
+			Usage: "fail calls not finished within `DURATION` like 30s or 2m from start, including retries. It bounds --retry and --call-timeout",
+		},
+		cli.StringSliceFlag{
+			Name:  "header, H",
+			Usage: "add custom `\"Name: Value\"` header to requests, can be repeated",
+		},
+		cli.StringFlag{
+			Name:   "proxy",
+			Usage:  "route requests through SOCKS5 proxy, e.g. socks5://127.0.0.1:9050 for Tor",
+			EnvVar: "BITCART_PROXY",
+		},
+		cli.IntFlag{
+			Name:  "retry",
+			Usage: "retry failed requests up to `N` times on connection errors, timeouts and 5xx responses",
+		},
+		cli.IntFlag{
+			Name:   "ip-version",
+			Usage:  "connect to daemon over IPv4 if `VERSION` is 4 or IPv6 if it is 6, e.g. when localhost resolves to ::1 but daemon listens on 127.0.0.1",
+			EnvVar: "BITCART_IP_VERSION",
+		},
+		cli.BoolFlag{
+			Name:  "insecure",
+			Usage: "skip TLS certificate verification for https URLs (development only)",
+		},
+		cli.StringFlag{
+			Name:   "client-cert",
+			Usage:  "authenticate to daemon with PEM client certificate from `FILE`, requires --client-key",
+			EnvVar: "BITCART_CLIENT_CERT",
+		},
+		cli.StringFlag{
+			Name:   "client-key",
+			Usage:  "read PEM private key of --client-cert from `FILE`",
+			EnvVar: "BITCART_CLIENT_KEY",
+		},
+		cli.StringSliceFlag{
+			Name:   "ca-cert",
+			Usage:  "trust CAs from PEM `FILE` instead of system ones for https URLs, can be repeated",
+			EnvVar: "BITCART_CA_CERT",
+		},
+		cli.BoolFlag{
+			Name:  "raw-args",
+			Usage: "pass all arguments as strings without JSON decoding",
+		},
+		cli.StringFlag{
+			Name:  "encode-params",
+			Usage: "send arguments read from stdin with \"-\" or from @file encoded as `ENCODING`: base64 or hex",
+		},
+		cli.StringFlag{
+			Name:  "params",
+			Usage: "use JSON array or object, or @file containing it, as params instead of arguments",
+		},
+		cli.StringFlag{
+			Name:  "params-file",
+			Usage: "use params from JSON, YAML (.yaml, .yml) or TOML (.toml) `FILE` instead of arguments",
+		},
+		cli.BoolFlag{
+			Name:  "stdin-request",
+			Usage: "read {coin, wallet, method, params} JSON object from stdin and make that call",
+		},
+		cli.StringFlag{
+			Name:  "batch",
+			Usage: "run calls from JSON file with array of {method, params} objects, sent as one JSON-RPC batch per daemon",
+		},
+		cli.IntFlag{
+			Name:  "concurrency",
+			Usage: "run at most `N` calls at once in --batch, --wallets and --coin all modes, 1 makes them sequential",
+			Value: 4,
+		},
+		cli.BoolFlag{
+			Name:  "stop-on-error",
+			Usage: "in --batch, --wallets and --coin all modes, don't start more calls after one fails",
+		},
+		cli.BoolFlag{
+			Name:  "keep-going",
+			Usage: "run the rest of methods chained with -- after one fails, e.g. bitcart-cli unlock pass -- getinfo",
+		},
+		cli.StringFlag{
+			Name:   "rpc-version",
+			Usage:  "send `VERSION` in jsonrpc field of requests, 1.0 or 2.0",
+			Value:  bitcart.DefaultRPCVersion,
+			EnvVar: "BITCART_RPC_VERSION",
+		},
+		cli.IntFlag{
+			Name:  "id",
+			Usage: "specify JSON-RPC request id, incremented for each following request",
+		},
+		cli.BoolFlag{
+			Name:  "paginate",
+			Usage: "fetch list result page by page, adding offset and count to named params, until a page is not full, and print all pages as one list",
+		},
+		cli.IntFlag{
+			Name:  "page-size",
+			Usage: "request `N` elements per page with --paginate",
+			Value: defaultPageSize,
+		},
+		cli.BoolFlag{
+			Name:  "no-id",
+			Usage: "send call as JSON-RPC notification without id and exit once it is sent, without waiting for result",
+		},
+		cli.BoolFlag{
+			Name:   "check-methods",
+			Usage:  "check methods against cached list of daemon methods, expanding unambiguous prefixes",
+			EnvVar: "BITCART_CHECK_METHODS",
+		},
+		cli.BoolFlag{
+			Name:  "strict",
+			Usage: "fail instead of warning when method is unknown to daemon, implies --check-methods",
+		},
+		cli.BoolFlag{
+			Name:  "no-expand",
+			Usage: "with --check-methods, don't expand unambiguous prefixes of method names like getinf to getinfo",
+		},
+		cli.BoolFlag{
+			Name:  "refresh-methods",
+			Usage: "rebuild cached list of daemon methods, implies --check-methods",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "print request which would be sent without sending it",
+		},
+		cli.StringFlag{
+			Name:  "mock",
+			Usage: "answer calls from JSON file with {method: {\"result\": ...} or {\"error\": ...}} object instead of daemon",
+		},
+		cli.BoolFlag{
+			Name:  "repl",
+			Usage: "start interactive session",
+		},
+		cli.StringFlag{
+			Name:  "script",
+			Usage: "run calls from `FILE`, or stdin if it is -, one \"method args\" line per call like in --repl, # starts a comment",
+		},
+		cli.StringFlag{
+			Name:   "history-file",
+			Usage:  "save REPL history to file, empty to disable",
+			Value:  defaultHistoryPath(),
+			EnvVar: "BITCART_HISTORY_FILE",
+		},
+		cli.IntFlag{
+			Name:  "watch",
+			Usage: "repeat the call every `N` seconds until interrupted",
+		},
+		cli.DurationFlag{
+			Name:  "cache",
+			Usage: "reuse results of read-only methods like getinfo for `TTL` like 500ms or 10s, in --watch, --repl, --script and --batch modes",
+		},
+		cli.IntFlag{
+			Name:  "repeat",
+			Usage: "make the call `N` times, up to --concurrency at once, and print latency statistics instead of results",
+		},
+		cli.IntFlag{
+			Name:  "skip-failed",
+			Usage: "with --coin all and ping, skip a coin for `N` seconds after its daemon fails, then try it again",
+		},
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "write result to file instead of stdout",
+		},
+		cli.IntFlag{
+			Name:  "max-output",
+			Usage: "print at most `BYTES` of result to stdout, --output files are never truncated",
+		},
+		cli.IntFlag{
+			Name:  "autofile",
+			Usage: "write results bigger than `BYTES` to a temporary file and print its path instead, --output files get every result",
+		},
+		cli.StringFlag{
+			Name:  "select",
+			Usage: "print only part of result at dotted `PATH`, e.g. result.confirmed or items[0].txid",
+		},
+		cli.StringSliceFlag{
+			Name:  "filter",
+			Usage: "keep elements of list result matching `EXPR` like label=coffee, height>1000 or amount<=0.5, after --select, can be repeated",
+		},
+		cli.BoolFlag{
+			Name:  "count",
+			Usage: "print number of elements of list result or keys of object result, after --select",
+		},
+		cli.BoolFlag{
+			Name:  "prom",
+			Usage: "print numeric result, or object of numeric fields, as Prometheus metric labelled with coin and wallet, after --select",
+		},
+		cli.StringFlag{
+			Name:  "metric-name",
+			Usage: "name metric printed by --prom `NAME`, default is bitcart_ and method name without get prefix",
+		},
+		cli.BoolFlag{
+			Name:  "with-meta",
+			Usage: "print {meta, result} object with call duration, HTTP status and URL instead of bare result",
+		},
+		cli.StringFlag{
+			Name:  "validate",
+			Usage: "check result against JSON Schema `FILE`, exit with code 9 listing violations if it doesn't match",
+		},
+		cli.StringSliceFlag{
+			Name:  "assert",
+			Usage: "fail if result doesn't satisfy `EXPR` like path==value, path!=value, path>value or path<value, can be repeated",
+		},
+		cli.BoolFlag{
+			Name:  "fail-on-empty",
+			Usage: "exit with code 8 if result is null, empty array or empty object",
+		},
+		cli.StringFlag{
+			Name:  "on-result",
+			Usage: "run shell `COMMAND` after each successful call with result JSON on stdin, and BITCART_COIN and BITCART_METHOD set",
+		},
+		cli.BoolFlag{
+			Name:  "hook-strict",
+			Usage: "fail with exit code of --on-result command if it fails, instead of printing a warning",
+		},
+		cli.StringSliceFlag{
+			Name:  "redact",
+			Usage: "replace values of comma-separated `FIELD`s at any depth of output with ***, can be repeated",
+		},
+		cli.BoolFlag{
+			Name:  "redact-secrets",
+			Usage: "redact " + strings.Join(secretFields, ", ") + " fields, like --redact",
+		},
+		cli.BoolFlag{
+			Name:  "compact",
+			Usage: "print JSON without indentation, default when stdout is not a terminal. BITCART_OUTPUT=pretty, compact, raw, yaml, table or csv sets default format",
+		},
+		cli.BoolFlag{
+			Name:  "raw",
+			Usage: "print result JSON exactly as returned by daemon",
+		},
+		cli.BoolFlag{
+			Name:  "table",
+			Usage: "print list of objects as table",
+		},
+		cli.BoolFlag{
+			Name:  "csv",
+			Usage: "print list of objects as CSV with header row, nested values are JSON-encoded",
+		},
+		cli.BoolFlag{
+			Name:  "yaml",
+			Usage: "print result as YAML",
+		},
+		cli.StringFlag{
+			Name:  "template",
+			Usage: "render result with Go text/template `TEMPLATE`, e.g. '{{ .confirmed }} confirmed', json function encodes a value",
+		},
+		cli.StringFlag{
+			Name:  "template-file",
+			Usage: "render result with Go text/template read from `FILE`",
+		},
+		cli.BoolFlag{
+			Name:  "ndjson",
+			Usage: "stream array result as one JSON element per line without loading it into memory",
+		},
+		cli.BoolFlag{
+			Name:  "color",
+			Usage: "always colorize JSON output",
+		},
+		cli.BoolFlag{
+			Name:  "no-color",
+			Usage: "never colorize JSON output, same as setting NO_COLOR",
+		},
+		cli.BoolFlag{
+			Name:  "yes, y",
+			Usage: "don't ask for confirmation before calling dangerous methods like payto or broadcast",
+		},
+		cli.BoolFlag{
+			Name:  "strict-confirm",
+			Usage: "fail dangerous method calls without terminal unless --yes is given",
+		},
+		cli.BoolFlag{
+			Name:  "error-json",
+			Usage: "report failures as {\"error\": {\"type\", \"message\", \"code\"}} JSON object",
+		},
+		cli.BoolFlag{
+			Name:  "error-stdout",
+			Usage: "print errors to stdout instead of stderr, useful with --error-json",
+		},
+		cli.BoolFlag{
+			Name:  "only-result",
+			Usage: "print only result of successful call, failed calls print nothing and only set exit code",
+		},
+		cli.BoolFlag{
+			Name:  "only-error",
+			Usage: "print only error of failed call, to stdout, successful calls print nothing",
+		},
+		cli.BoolFlag{
+			Name:  "legacy-errors",
+			Usage: "print failed call to stdout, JSON-RPC errors as indented JSON, and exit 0 like old versions did, for scripts parsing stdout",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "print nothing but the result, errors are reported only by exit code",
+		},
+		cli.BoolFlag{
+			Name:  "verbose, v",
+			Usage: "log requests and responses to stderr",
+		},
+		cli.BoolFlag{
+			Name:  "vv",
+			Usage: "like --verbose, but don't redact authorization header",
+		},
+		cli.StringFlag{
+			Name:   "log-file",
+			Usage:  "append JSON lines log of calls to file, \"-\" for stderr",
+			EnvVar: "BITCART_LOG_FILE",
+		},
+		cli.StringFlag{
+			Name:   "log-level",
+			Usage:  "log records of `LEVEL` and above: debug, info, warn or error",
+			Value:  "info",
+			EnvVar: "BITCART_LOG_LEVEL",
+		},
+		cli.StringFlag{
+			Name:  "trace-file",
+			Usage: "write every request and response with headers, bodies and timing to `FILE` as JSON array when done, secret headers are redacted",
+		},
 	}
-	app.Action = func(c *cli.Context) error {
-		args := c.Args()
-		if len(args) >= 1 {
-			// load flags
-			wallet := c.String("wallet")
-			user := c.String("user")
-			password := c.String("password")
-			coin := c.String("coin")
-			// initialize rpc client
-			rpcClient := jsonrpc.NewClientWithOpts(COINS[coin], &jsonrpc.RPCClientOpts{
-				CustomHeaders: map[string]string{
-					"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password)),
-				},
+	var cfg *config
+	// h remembers failed daemons between repeated --coin all calls
+	var h *health
+	app.Before = func(c *cli.Context) error {
+		errorJSON, errorStdout = c.Bool("error-json"), c.Bool("error-stdout")
+		if legacyErrors = c.Bool("legacy-errors"); legacyErrors && errorJSON {
+			return fail("--legacy-errors can't be combined with --error-json", exitFailure)
+		}
+		// set once checked, as --only-result hides errors
+		if c.Bool("only-result") && c.Bool("only-error") {
+			return fail("--only-result can't be combined with --only-error", exitFailure)
+		}
+		if legacyErrors && (c.Bool("only-result") || c.Bool("only-error")) {
+			return fail("--legacy-errors can't be combined with --only-result or --only-error", exitFailure)
+		}
+		onlyResult, onlyError = c.Bool("only-result"), c.Bool("only-error")
+		h = newHealth(time.Duration(c.Int("skip-failed")) * time.Second)
+		if d := c.Duration("deadline"); d > 0 {
+			deadline = time.Now().Add(d)
+		}
+		if c.Bool("quiet") {
+			stderr = ioutil.Discard
+		}
+		// logging is off unless a log file is given
+		if path := c.String("log-file"); path != "" {
+			var err error
+			if callLog, err = newLogger(path, c.String("log-level")); err != nil {
+				return fail(err, exitFailure)
+			}
+		}
+		if path := c.String("trace-file"); path != "" {
+			var err error
+			if callTrace, err = newTrace(path); err != nil {
+				return fail(err, exitFailure)
+			}
+		}
+		// output format flags win over BITCART_OUTPUT, which wins over terminal detection
+		outputFlag, err := defaultOutputFlag(c)
+		if err != nil {
+			return fail(err, exitFailure)
+		}
+		if outputFlag != "" {
+			c.Set(outputFlag, "true")
+		}
+		// coin URLs are resolved in order of increasing precedence:
+		// built-in defaults, --coin-file, config file, BITCART_<COIN>_URL environment variables, --url flag.
+		// --host and --port rewrite the resolved URLs
+		if path := c.String("coin-file"); path != "" {
+			coins, err := loadCoinFile(path)
+			if err != nil {
+				return fail(err, exitFailure)
+			}
+			for name, url := range coins {
+				registry.Set(name, url, bitcart.SourceFile)
+			}
+		}
+		// config init creates config file, so it may not exist yet
+		creating := c.Args().Get(0) == "config" && c.Args().Get(1) == "init"
+		cfg, err = loadConfig(c.String("config"), c.IsSet("config") && !creating)
+		if err != nil {
+			return fail(err, exitFailure)
+		}
+		if profile := c.String("profile"); profile != "" {
+			if err := cfg.useProfile(profile); err != nil {
+				return fail(err, exitFailure)
+			}
+		}
+		if cfg.Timeout != 0 && !c.IsSet("timeout") {
+			c.Set("timeout", strconv.Itoa(cfg.Timeout))
+		}
+		if cfg.Proxy != "" && !c.IsSet("proxy") {
+			c.Set("proxy", cfg.Proxy)
+		}
+		for name, coinCfg := range cfg.Coins {
+			if url, fallbacks := coinCfg.urls(); url != "" {
+				registry.Set(name, url, bitcart.SourceConfig)
+				fallbackURLs[name] = fallbacks
+			}
+		}
+		for name, url := range bitcart.EnvCoinURLs(os.Environ()) {
+			registry.Set(name, url, bitcart.SourceEnv)
+		}
+		// aliases are resolved once, so the rest of the run sees coin names only
+		for alias, coin := range cfg.CoinAliases {
+			coinAliases[strings.ToLower(alias)] = coin
+		}
+		if coin := resolveCoin(c.String("coin"), registry.Coins()); coin != c.String("coin") {
+			c.Set("coin", coin)
+		}
+		// host applies to every coin, port only to selected one
+		if host := c.String("host"); c.IsSet("host") {
+			err = registry.Rewrite(func(_, coinURL string) (string, error) {
+				return bitcart.RewriteURL(coinURL, host, "")
 			})
-			// call RPC method
-			result, err := rpcClient.Call(args[0], wallet, args[1:])
 			if err != nil {
-				fmt.Println("Error:", err)
-				return nil
+				return fail(err, exitFailure)
 			}
-			// Print either error if found or result
-			var b []byte
-			if result.Error != nil {
-				b, err = json.MarshalIndent(result.Error, "", "  ")
-			} else {
-				b, err = json.MarshalIndent(result.Result, "", "  ")
+			for _, fallbacks := range fallbackURLs {
+				for i, fallbackURL := range fallbacks {
+					if fallbacks[i], err = bitcart.RewriteURL(fallbackURL, host, ""); err != nil {
+						return fail(err, exitFailure)
+					}
+				}
 			}
+		}
+		if port := c.String("port"); port != "" {
+			err = registry.Rewrite(func(name, coinURL string) (string, error) {
+				if name != c.String("coin") {
+					return coinURL, nil
+				}
+				return bitcart.RewriteURL(coinURL, "", port)
+			})
 			if err != nil {
-				fmt.Println("error:", err)
-				return nil
+				return fail(err, exitFailure)
 			}
-			fmt.Println(string(b))
-		} else {
-			cli.ShowAppHelp(c)
 		}
+		// explicit URL overrides selected coin
+		if url := c.String("url"); url != "" && c.String("coin") != allCoins {
+			registry.Set(c.String("coin"), url, bitcart.SourceFlag)
+		}
+		// fallbacks only back up daemon from config file
+		for name := range fallbackURLs {
+			if registry.Source(name) != bitcart.SourceConfig {
+				delete(fallbackURLs, name)
+			}
+		}
+		COINS = registry.Coins()
 		return nil
 	}
+	app.Commands = []cli.Command{
+		{
+			Name:  "list-coins",
+			Usage: "list configured coins and their daemon URLs",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "json",
+					Usage: "print coins as JSON",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return listCoins(COINS, c.Bool("json"))
+			},
+		},
+		{
+			Name:  "methods",
+			Usage: "list RPC methods supported by daemon",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "describe",
+					Usage: "show help text of each method",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				s, err := newSession(c.Parent(), c.GlobalString("coin"), COINS, cfg)
+				if err != nil {
+					return fail(err, exitFailure)
+				}
+				return s.listMethods(c.Bool("describe"))
+			},
+		},
+		{
+			Name:  "ping",
+			Usage: "check that daemon responds and print latency, use --coin all to ping every coin",
+			Action: func(c *cli.Context) error {
+				coins := []string{c.GlobalString("coin")}
+				if coins[0] == allCoins {
+					coins = bitcart.SortedCoins(COINS)
+				}
+				ping := func() error {
+					return runPing(coins, func(coin string) (*session, error) {
+						return newSession(c.Parent(), coin, COINS, cfg)
+					}, h)
+				}
+				if watch := time.Duration(c.GlobalInt("watch")) * time.Second; watch > 0 {
+					return repeat(watch, "ping", true, func() { ping() })
+				}
+				return ping()
+			},
+		},
+		{
+			Name:  "doctor",
+			Usage: "check reachability, authentication, latency and version of every coin daemon, or of selected one if --coin is set",
+			Action: func(c *cli.Context) error {
+				coins := bitcart.SortedCoins(COINS)
+				if coin := c.GlobalString("coin"); c.GlobalIsSet("coin") && coin != allCoins {
+					coins = []string{coin}
+				}
+				return runDoctor(coins, func(coin string) (*session, error) {
+					return newSession(c.Parent(), coin, COINS, cfg)
+				})
+			},
+		},
+		{
+			Name:      "diff",
+			Usage:     "call method on two daemons and print differences between their results, failing if they differ",
+			ArgsUsage: "<method> [args]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "coin-a",
+					Usage: "first daemon, as coin name or URL of selected coin daemon",
+				},
+				cli.StringFlag{
+					Name:  "coin-b",
+					Usage: "second daemon, as coin name or URL of selected coin daemon",
+				},
+				cli.StringSliceFlag{
+					Name:  "ignore",
+					Usage: "field to leave out of comparison, by name or path like result.height, can be repeated or comma-separated",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return diffCommand(c, COINS, cfg)
+			},
+		},
+		{
+			Name:  "version",
+			Usage: "print CLI version and daemon version of selected coin, or of every coin with --coin all",
+			Action: func(c *cli.Context) error {
+				coins := []string{c.GlobalString("coin")}
+				if coins[0] == allCoins {
+					coins = bitcart.SortedCoins(COINS)
+				}
+				return printVersions(app.Version, coins, func(coin string) (*session, error) {
+					return newSession(c.Parent(), coin, COINS, cfg)
+				})
+			},
+		},
+		{
+			Name:  "wallets",
+			Usage: "list wallets loaded by daemon of selected coin, or of every coin with --coin all",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "json",
+					Usage: "print wallets as JSON",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				coins := []string{c.GlobalString("coin")}
+				if coins[0] == allCoins {
+					coins = bitcart.SortedCoins(COINS)
+				}
+				return printWallets(coins, func(coin string) (*session, error) {
+					return newSession(c.Parent(), coin, COINS, cfg)
+				}, c.Bool("json"))
+			},
+		},
+		{
+			Name:      "create-wallet",
+			Usage:     "create wallet in daemon wallets directory and print its seed",
+			ArgsUsage: "NAME",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "wallet-password",
+					Usage: "encrypt wallet with `PASSWORD`, asked on terminal if not given",
+				},
+				cli.StringFlag{
+					Name:  "passphrase",
+					Usage: "extend seed with `PASSPHRASE`",
+				},
+				cli.StringFlag{
+					Name:  "seed-type",
+					Usage: "create seed of `TYPE`, like segwit or standard, daemon default if empty",
+				},
+				cli.BoolFlag{
+					Name:  "no-encrypt-file",
+					Usage: "only encrypt private keys with password, not the whole wallet file",
+				},
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "create wallet even if daemon already has one with this name",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 1 {
+					return fail("expected wallet name, e.g. bitcart-cli create-wallet mywallet", exitFailure)
+				}
+				opts := walletOptions{
+					password:   c.String("wallet-password"),
+					passphrase: c.String("passphrase"),
+					seedType:   c.String("seed-type"),
+					encrypt:    !c.Bool("no-encrypt-file"),
+					force:      c.Bool("force"),
+				}
+				if !c.IsSet("wallet-password") && isTerminal(os.Stdin) {
+					var err error
+					if opts.password, err = promptWalletPassword(); err != nil {
+						return fail(err, exitFailure)
+					}
+				}
+				s, err := newSession(c.Parent(), c.GlobalString("coin"), COINS, cfg)
+				if err != nil {
+					return fail(err, exitFailure)
+				}
+				return s.createWallet(c.Args().First(), opts)
+			},
+		},
+		{
+			Name:      "decode",
+			Usage:     "decode base64 or hex field of result read from stdin",
+			ArgsUsage: " ",
+			Description: "Decodes a field of already fetched result, e.g.\n" +
+				"   bitcart-cli gettransaction txid | bitcart-cli decode --hex --field hex > tx.bin",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "base64",
+					Usage: "field is base64-encoded",
+				},
+				cli.BoolFlag{
+					Name:  "hex",
+					Usage: "field is hex-encoded",
+				},
+				cli.StringFlag{
+					Name:  "field",
+					Usage: "decode value at dotted `PATH` like --select, whole result if empty",
+				},
+				cli.StringFlag{
+					Name:  "output, o",
+					Usage: "write decoded bytes to file instead of stdout",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if c.Bool("base64") == c.Bool("hex") {
+					return fail("choose one of --base64 or --hex", exitFailure)
+				}
+				encoding := "hex"
+				if c.Bool("base64") {
+					encoding = "base64"
+				}
+				return runDecode(c.String("field"), encoding, c.String("output"))
+			},
+		},
+		{
+			Name:  "config",
+			Usage: "inspect and write configuration",
+			Subcommands: []cli.Command{
+				{
+					Name:  "show",
+					Usage: "print effective configuration after merging config file, environment and flags, with secrets redacted",
+					Action: func(c *cli.Context) error {
+						root := rootContext(c)
+						value, err := effectiveConfig(root, COINS, cfg)
+						if err != nil {
+							return fail(err, exitFailure)
+						}
+						return newOutputOptions(root).write(value)
+					},
+				},
+				{
+					Name:  "init",
+					Usage: "write settings resolved from flags, environment and config file to config file at --config path",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "force",
+							Usage: "overwrite existing config file",
+						},
+						cli.BoolFlag{
+							Name:  "secrets",
+							Usage: "include passwords without asking",
+						},
+						cli.BoolFlag{
+							Name:  "no-secrets",
+							Usage: "leave passwords out without asking",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						return initConfig(c, rootContext(c).String("config"), COINS, cfg, c.Bool("force"))
+					},
+				},
+			},
+		},
+		{
+			Name:      "completion",
+			Usage:     "print shell completion script",
+			ArgsUsage: "bash|zsh",
+			Action: func(c *cli.Context) error {
+				return printCompletion(c.Args().First())
+			},
+		},
+	}
+	app.BashComplete = func(c *cli.Context) {
+		// completion runs before app.Before, so config has to be loaded here
+		if err := app.Before(c); err != nil {
+			return
+		}
+		completeApp(c, COINS)
+	}
+	// interrupted runs print completed results and exit with exitInterrupted
+	action := func(c *cli.Context) error {
+		args := append([]string{}, c.Args()...)
+		batchFile := c.String("batch")
+		repl := c.Bool("repl")
+		script := c.String("script")
+		if c.Bool("stdin-request") {
+			if len(args) > 0 || batchFile != "" || repl || script != "" {
+				return fail("--stdin-request can't be combined with arguments, --batch, --repl or --script", exitFailure)
+			}
+			return runStdinRequest(c, COINS, cfg)
+		}
+		if script != "" && (len(args) > 0 || batchFile != "" || repl) {
+			return fail("--script can't be combined with arguments, --batch or --repl", exitFailure)
+		}
+		// calls of batch, REPL and script modes come from their input
+		multi := batchFile != "" || repl || script != ""
+		if len(args) == 0 && !multi {
+			// flags without method most likely mean it was forgotten
+			if cfg.DefaultMethod == "" && c.NumFlags() > 0 && !c.Bool("help") {
+				if errorJSON {
+					return fail("no method specified", exitFailure)
+				}
+				fmt.Fprint(stderr, noMethodHelp)
+				return cli.NewExitError("", exitFailure)
+			}
+			if cfg.DefaultMethod == "" {
+				if !c.Bool("quiet") {
+					cli.ShowAppHelp(c)
+				}
+				return nil
+			}
+			args = []string{cfg.DefaultMethod}
+		}
+		chain, err := splitChain(args)
+		if err != nil && !multi {
+			return fail(err, exitFailure)
+		}
+		if len(chain) > 1 && (multi || c.Int("watch") > 0 || c.Int("repeat") > 0 || c.String("coin") == allCoins ||
+			len(c.StringSlice("wallets")) > 0 || c.String("params") != "" || c.String("params-file") != "") {
+			return fail("chained methods can't be combined with --batch, --repl, --script, --watch, --repeat, --wallets, --params or --coin all", exitFailure)
+		}
+		watch := time.Duration(c.Int("watch")) * time.Second
+		if watch > 0 && multi {
+			return fail("--watch can't be combined with --batch, --repl or --script", exitFailure)
+		}
+		repeatCount := c.Int("repeat")
+		if repeatCount > 0 && (multi || watch > 0 || c.String("coin") == allCoins || len(c.StringSlice("wallets")) > 0) {
+			return fail("--repeat can't be combined with --batch, --repl, --script, --watch, --wallets or --coin all", exitFailure)
+		}
+		// results of many calls are printed as one object, not as metrics, and aren't checked one by one
+		fanout := batchFile != "" || c.String("coin") == allCoins || len(c.StringSlice("wallets")) > 0
+		if c.Bool("prom") && fanout {
+			return fail("--prom can't be combined with --batch, --wallets or --coin all", exitFailure)
+		}
+		for _, name := range []string{"assert", "fail-on-empty", "validate", "select", "filter", "count"} {
+			if c.IsSet(name) && fanout {
+				return fail(fmt.Sprintf("--%s can't be combined with --batch, --wallets or --coin all", name), exitFailure)
+			}
+		}
+		if c.String("coin") == allCoins {
+			if multi {
+				return fail("--coin all can't be combined with --batch, --repl or --script", exitFailure)
+			}
+			args[0] = cfg.expandAlias(args[0])
+			params, err := commandParams(args[1:], c.String("params"), c.String("params-file"), c.Bool("raw-args"), c.String("encode-params"), os.Stdin)
+			if err != nil {
+				return fail(err, exitFailure)
+			}
+			if args[0], params, err = applyShim(cfg.Shims, args[0], params); err != nil {
+				return fail(err, exitFailure)
+			}
+			if err := newConfirmation(c, cfg).check(args[0], "all coins", askStdin); err != nil {
+				return err
+			}
+			output := newOutputOptions(c)
+			run := func() error {
+				return runCoins(bitcart.SortedCoins(COINS), func(coin string) (*session, error) {
+					return newSession(c, coin, COINS, cfg)
+				}, output, c.Int("concurrency"), c.Bool("stop-on-error"), h, args[0], params)
+			}
+			if watch > 0 {
+				return repeat(watch, args[0], output.file == "", func() { run() })
+			}
+			return run()
+		}
+		s, err := newSession(c, c.String("coin"), COINS, cfg)
+		if err != nil {
+			return fail(err, exitFailure)
+		}
+		if batchFile != "" {
+			return s.runBatch(batchFile, func(coin string) (*session, error) {
+				return newSession(c, resolveCoin(coin, COINS), COINS, cfg)
+			})
+		}
+		if repl {
+			return s.runREPL(c.Bool("raw-args"), c.String("history-file"))
+		}
+		if script != "" {
+			return s.runScript(script, c.Bool("raw-args"))
+		}
+		if len(chain) > 1 {
+			return s.runChain(chain, c.Bool("raw-args"), c.String("encode-params"))
+		}
+		params, err := commandParams(args[1:], c.String("params"), c.String("params-file"), c.Bool("raw-args"), c.String("encode-params"), os.Stdin)
+		if err != nil {
+			return fail(err, exitFailure)
+		}
+		if args[0], params, err = s.prepareCall(args[0], params, askStdin); err != nil {
+			return err
+		}
+		if wallets := splitList(c.StringSlice("wallets")); len(wallets) > 0 {
+			return s.runWallets(wallets, args[0], params)
+		}
+		if watch > 0 {
+			return s.watch(watch, args[0], params)
+		}
+		if repeatCount > 0 {
+			return s.benchmark(repeatCount, args[0], params)
+		}
+		return s.call(args[0], params)
+	}
+	app.Action = func(c *cli.Context) error {
+		err := action(c)
+		if interrupted() {
+			return cli.NewExitError("", exitInterrupted)
+		}
+		return err
+	}
 
+	handleSignals()
+	// cli exits on errors with exit codes from within Run, trace is written either way
+	cli.OsExiter = func(code int) {
+		writeTrace()
+		os.Exit(code)
+	}
 	err := app.Run(os.Args)
+	writeTrace()
 	if err != nil {
+		callLog.log(levelError, "exit", map[string]interface{}{"error": err.Error()})
+		if errorJSON {
+			reportError(errorInfo{Type: "error", Message: err.Error()})
+			os.Exit(exitFailure)
+		}
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,49 @@
+package bitcart
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// AuthHeader builds Authorization header value, bearer token takes precedence over basic auth
+func AuthHeader(user, password, token string) string {
+	if token != "" {
+		return "Bearer " + token
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+}
+
+// IsSecretHeader reports whether header value should be redacted in logs
+func IsSecretHeader(name string) bool {
+	name = strings.ToLower(name)
+	return name == "authorization" || strings.Contains(name, "key") ||
+		strings.Contains(name, "token") || strings.Contains(name, "secret")
+}
+
+// RedactHeaders returns copy of headers with secret values redacted
+func RedactHeaders(headers map[string]string) map[string]string {
+	result := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if IsSecretHeader(name) {
+			value = "<redacted>"
+		}
+		result[name] = value
+	}
+	return result
+}
+
+// ParseHeaders parses "Name: Value" strings into header map
+func ParseHeaders(headers []string) (map[string]string, error) {
+	result := make(map[string]string, len(headers))
+	for _, header := range headers {
+		parts := strings.SplitN(header, ":", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" {
+			return nil, fmt.Errorf("invalid header %q: expected \"Name: Value\"", header)
+		}
+		result[http.CanonicalHeaderKey(name)] = strings.TrimSpace(parts[1])
+	}
+	return result, nil
+}
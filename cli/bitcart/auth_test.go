@@ -0,0 +1,61 @@
+package bitcart
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAuthHeader(t *testing.T) {
+	tests := []struct {
+		user, password, token string
+		want                  string
+	}{
+		{"electrum", "electrumz", "", "Basic ZWxlY3RydW06ZWxlY3RydW16"},
+		{"electrum", "electrumz", "abc", "Bearer abc"},
+		{"", "", "", "Basic Og=="},
+	}
+	for _, test := range tests {
+		if got := AuthHeader(test.user, test.password, test.token); got != test.want {
+			t.Errorf("AuthHeader(%q, %q, %q) = %q, want %q", test.user, test.password, test.token, got, test.want)
+		}
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	got, err := ParseHeaders([]string{"x-api-key: secret", "Accept:application/json", "X-Empty:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"X-Api-Key": "secret", "Accept": "application/json", "X-Empty": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseHeaders() = %v, want %v", got, want)
+	}
+	for _, header := range []string{"no colon", ": value"} {
+		if _, err := ParseHeaders([]string{header}); err == nil {
+			t.Errorf("ParseHeaders(%q) succeeded, want error", header)
+		}
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	headers := map[string]string{
+		"Authorization":   "Basic abc",
+		"X-Api-Key":       "k",
+		"X-Auth-Token":    "t",
+		"X-Client-Secret": "s",
+		"Accept":          "application/json",
+	}
+	want := map[string]string{
+		"Authorization":   "<redacted>",
+		"X-Api-Key":       "<redacted>",
+		"X-Auth-Token":    "<redacted>",
+		"X-Client-Secret": "<redacted>",
+		"Accept":          "application/json",
+	}
+	if got := RedactHeaders(headers); !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactHeaders() = %v, want %v", got, want)
+	}
+	if headers["Authorization"] != "Basic abc" {
+		t.Error("RedactHeaders() modified its argument")
+	}
+}
@@ -0,0 +1,323 @@
+// Package bitcart implements a client for Bitcart daemons JSON-RPC API.
+// It is used by bitcart-cli and can be embedded in other tools
+package bitcart
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MrNaif2018/jsonrpc"
+)
+
+// RetryDelay is the delay before the first retry, doubled on each attempt
+const RetryDelay = 500 * time.Millisecond
+
+// DefaultRPCVersion is JSON-RPC version sent in requests unless configured otherwise
+const DefaultRPCVersion = "2.0"
+
+// Options configure a Client
+type Options struct {
+	// Wallet is sent with every call made by Call
+	Wallet   string
+	User     string
+	Password string
+	// Token is bearer token used instead of User and Password
+	Token string
+	// Headers are added to every request, Authorization header overrides credentials
+	Headers map[string]string
+	// UserAgent is sent as User-Agent header, unless it is set in Headers
+	UserAgent string
+	// HTTPClient is used for requests, if nil it is created from HTTP
+	HTTPClient *http.Client
+	HTTP       HTTPOptions
+	// Retries is how many times transient failures are retried
+	Retries int
+	// RPCVersion is value of jsonrpc field of requests, "1.0" or "2.0", DefaultRPCVersion if empty
+	RPCVersion string
+	// Fallbacks are URLs of backup daemons tried in order when daemon can't be connected to.
+	// They share HTTPClient if it is set
+	Fallbacks []string
+}
+
+// Client calls RPC methods of a single daemon
+type Client struct {
+	// URL is daemon endpoint
+	URL    string
+	Wallet string
+	// Headers are sent with every request, including Authorization
+	Headers    map[string]string
+	Retries    int
+	RPCVersion string
+	// OnRetry, if set, is called before retrying failed request
+	OnRetry func(request *jsonrpc.RPCRequest, attempt int, delay time.Duration, err error)
+	// OnFailover, if set, is called before switching from unreachable daemon to the next fallback
+	OnFailover func(request *jsonrpc.RPCRequest, from, to string, err error)
+
+	// endpoint is URL requests are sent to, it differs from URL for Unix sockets
+	endpoint   string
+	rpc        jsonrpc.RPCClient
+	httpClient *http.Client
+	// fallbacks are clients of backup daemons, active is index of the one used last, 0 is client itself
+	fallbacks []*Client
+	mu        sync.Mutex
+	nextID    int
+	active    int
+}
+
+// NewClient creates client for daemon at url
+func NewClient(url string, opts Options) (*Client, error) {
+	version := opts.RPCVersion
+	if version == "" {
+		version = DefaultRPCVersion
+	}
+	if version != "1.0" && version != "2.0" {
+		return nil, fmt.Errorf("unsupported JSON-RPC version %q, expected 1.0 or 2.0", version)
+	}
+	headers := make(map[string]string, len(opts.Headers)+1)
+	for name, value := range opts.Headers {
+		headers[name] = value
+	}
+	// custom headers can override authorization
+	if _, ok := headers["Authorization"]; !ok {
+		headers["Authorization"] = AuthHeader(opts.User, opts.Password, opts.Token)
+	}
+	if _, ok := headers["User-Agent"]; !ok && opts.UserAgent != "" {
+		headers["User-Agent"] = opts.UserAgent
+	}
+	endpoint := url
+	httpOpts := opts.HTTP
+	if socket, httpURL, ok := UnixSocketURL(url); ok {
+		endpoint = httpURL
+		httpOpts.Socket = socket
+	}
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		var err error
+		if httpClient, err = NewHTTPClient(httpOpts); err != nil {
+			return nil, err
+		}
+	}
+	fallbacks := make([]*Client, len(opts.Fallbacks))
+	for i, fallbackURL := range opts.Fallbacks {
+		fallbackOpts := opts
+		fallbackOpts.Fallbacks = nil
+		var err error
+		if fallbacks[i], err = NewClient(fallbackURL, fallbackOpts); err != nil {
+			return nil, fmt.Errorf("fallback %s: %v", fallbackURL, err)
+		}
+	}
+	return &Client{
+		URL:        url,
+		Wallet:     opts.Wallet,
+		Headers:    headers,
+		Retries:    opts.Retries,
+		RPCVersion: version,
+		endpoint:   endpoint,
+		httpClient: httpClient,
+		fallbacks:  fallbacks,
+		rpc: jsonrpc.NewClientWithOpts(endpoint, &jsonrpc.RPCClientOpts{
+			HTTPClient:    httpClient,
+			CustomHeaders: headers,
+		}),
+	}, nil
+}
+
+// WithAuth returns client sharing connections of c, which authenticates with other credentials.
+// Token is used instead of user and password if set, like in Options. Fallbacks get the same credentials
+func (c *Client) WithAuth(user, password, token string) *Client {
+	headers := make(map[string]string, len(c.Headers))
+	for name, value := range c.Headers {
+		headers[name] = value
+	}
+	headers["Authorization"] = AuthHeader(user, password, token)
+	fallbacks := make([]*Client, len(c.fallbacks))
+	for i, fallback := range c.fallbacks {
+		fallbacks[i] = fallback.WithAuth(user, password, token)
+	}
+	c.mu.Lock()
+	active := c.active
+	c.mu.Unlock()
+	return &Client{
+		URL:        c.URL,
+		Wallet:     c.Wallet,
+		Headers:    headers,
+		Retries:    c.Retries,
+		RPCVersion: c.RPCVersion,
+		OnRetry:    c.OnRetry,
+		OnFailover: c.OnFailover,
+		endpoint:   c.endpoint,
+		httpClient: c.httpClient,
+		fallbacks:  fallbacks,
+		active:     active,
+		rpc: jsonrpc.NewClientWithOpts(c.endpoint, &jsonrpc.RPCClientOpts{
+			HTTPClient:    c.httpClient,
+			CustomHeaders: headers,
+		}),
+	}
+}
+
+// IsTimeout reports whether err was caused by http client timeout.
+// jsonrpc wraps transport errors as plain strings, so the message is checked
+func IsTimeout(err error) bool {
+	return strings.Contains(err.Error(), "Client.Timeout exceeded")
+}
+
+// IsTransient reports whether failed call is worth retrying:
+// connection failures, timeouts and 5xx responses
+func IsTransient(err error) bool {
+	if httpErr, ok := err.(*jsonrpc.HTTPError); ok {
+		return httpErr.Code >= 500
+	}
+	message := err.Error()
+	return IsTimeout(err) ||
+		strings.Contains(message, "connection refused") ||
+		strings.Contains(message, "connection reset") ||
+		strings.Contains(message, "EOF")
+}
+
+// IsConnectionFailure reports whether err means daemon couldn't be connected to.
+// The request was never sent then, so it is safe to send it to another daemon
+func IsConnectionFailure(err error) bool {
+	if _, ok := err.(*jsonrpc.HTTPError); ok {
+		return false
+	}
+	message := err.Error()
+	return strings.Contains(message, "dial ") || strings.Contains(message, "connection refused")
+}
+
+// IsBatchRejected reports whether batch sent by SendBatchContext failed because daemon doesn't accept
+// batches: it returned HTTP 4xx, or a single response object instead of an array. The batch wasn't run then
+func IsBatchRejected(err error) bool {
+	if httpErr, ok := err.(*jsonrpc.HTTPError); ok {
+		return httpErr.Code >= 400 && httpErr.Code < 500
+	}
+	return strings.Contains(err.Error(), "cannot unmarshal object into Go value")
+}
+
+// Call calls RPC method with client wallet. Request ids start at 0 and are incremented on each call.
+// JSON-RPC errors are returned in response, err is only set on transport failures
+func (c *Client) Call(method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	return c.Send(c.newRequest(method, params...))
+}
+
+// newRequest returns request for method with client wallet and next request id
+func (c *Client) newRequest(method string, params ...interface{}) *jsonrpc.RPCRequest {
+	c.mu.Lock()
+	id := c.nextID
+	c.nextID++
+	c.mu.Unlock()
+	return &jsonrpc.RPCRequest{
+		Method:  method,
+		Params:  jsonrpc.Params(params...),
+		ID:      id,
+		JSONRPC: c.RPCVersion,
+		Xpub:    c.Wallet,
+	}
+}
+
+// Send sends request, retrying transient failures with exponential backoff.
+// If daemon can't be connected to, fallbacks are tried first, the one which responds is used for next requests.
+// JSON-RPC errors are returned in response and never retried
+func (c *Client) Send(request *jsonrpc.RPCRequest) (*jsonrpc.RPCResponse, error) {
+	return c.SendContext(context.Background(), request)
+}
+
+// SendContext is like Send, but requests and waits between retries are cancelled with ctx
+func (c *Client) SendContext(ctx context.Context, request *jsonrpc.RPCRequest) (*jsonrpc.RPCResponse, error) {
+	delay := RetryDelay
+	for attempt := 0; ; attempt++ {
+		result, err := c.failover(ctx, request)
+		if err == nil || attempt >= c.Retries || !IsTransient(err) || ctx.Err() != nil {
+			return result, err
+		}
+		if c.OnRetry != nil {
+			c.OnRetry(request, attempt+1, delay, err)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return result, err
+		}
+		delay *= 2
+	}
+}
+
+// SendBatchContext sends requests to active daemon as one JSON-RPC batch array, bound to ctx.
+// Responses are returned in order of requests, matched by id, with nil for requests daemon didn't answer.
+// Failures aren't retried and fallbacks aren't tried. Daemons not supporting batches fail with an error
+// for which IsBatchRejected is true, callers can send requests separately then
+func (c *Client) SendBatchContext(ctx context.Context, requests []*jsonrpc.RPCRequest) ([]*jsonrpc.RPCResponse, error) {
+	c.mu.Lock()
+	client := append([]*Client{c}, c.fallbacks...)[c.active]
+	c.mu.Unlock()
+	rpc := client.rpc
+	if ctx != context.Background() {
+		rpc = client.contextRPC(ctx)
+	}
+	responses, err := rpc.CallBatchRaw(requests)
+	if err != nil {
+		return nil, err
+	}
+	byID := responses.AsMap()
+	results := make([]*jsonrpc.RPCResponse, len(requests))
+	for i, request := range requests {
+		results[i] = byID[request.ID]
+	}
+	return results, nil
+}
+
+// failover sends request to active daemon, switching to the next one while they can't be connected to
+func (c *Client) failover(ctx context.Context, request *jsonrpc.RPCRequest) (*jsonrpc.RPCResponse, error) {
+	clients := append([]*Client{c}, c.fallbacks...)
+	c.mu.Lock()
+	active := c.active
+	c.mu.Unlock()
+	for i := 0; ; i++ {
+		client := clients[(active+i)%len(clients)]
+		rpc := client.rpc
+		if ctx != context.Background() {
+			rpc = client.contextRPC(ctx)
+		}
+		result, err := rpc.CallRaw(request)
+		if err == nil || i == len(clients)-1 || !IsConnectionFailure(err) || ctx.Err() != nil {
+			if err == nil {
+				c.mu.Lock()
+				c.active = (active + i) % len(clients)
+				c.mu.Unlock()
+			}
+			return result, err
+		}
+		if c.OnFailover != nil {
+			c.OnFailover(request, client.URL, clients[(active+i+1)%len(clients)].URL, err)
+		}
+	}
+}
+
+// contextRPC returns jsonrpc client sharing client connections, with requests bound to ctx.
+// jsonrpc doesn't take contexts, so it is set by http transport
+func (c *Client) contextRPC(ctx context.Context) jsonrpc.RPCClient {
+	httpClient := *c.httpClient
+	httpClient.Transport = &contextTransport{ctx: ctx, transport: c.httpClient.Transport}
+	return jsonrpc.NewClientWithOpts(c.endpoint, &jsonrpc.RPCClientOpts{
+		HTTPClient:    &httpClient,
+		CustomHeaders: c.Headers,
+	})
+}
+
+// contextTransport is http transport binding requests to context
+type contextTransport struct {
+	ctx       context.Context
+	transport http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return transport.RoundTrip(req.WithContext(t.ctx))
+}
@@ -0,0 +1,440 @@
+package bitcart
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/MrNaif2018/jsonrpc"
+)
+
+// testDaemon returns server answering each request with result of handle
+func testDaemon(t *testing.T, handle func(request map[string]interface{}, r *http.Request) (int, interface{})) *httptest.Server {
+	return httptest.NewServer(testHandler(t, handle))
+}
+
+// testHandler returns http handler answering each request with result of handle
+func testHandler(t *testing.T, handle func(request map[string]interface{}, r *http.Request) (int, interface{})) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		status, response := handle(request, r)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+	})
+}
+
+func TestClientCall(t *testing.T) {
+	var requests []map[string]interface{}
+	var auth string
+	server := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+		requests = append(requests, request)
+		auth = r.Header.Get("Authorization")
+		return http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": request["id"], "result": "ok"}
+	})
+	defer server.Close()
+	client, err := NewClient(server.URL, Options{Wallet: "xpub1", User: "electrum", Password: "electrumz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		response, err := client.Call("getbalance", map[string]interface{}{"a": 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Error != nil || response.Result != "ok" {
+			t.Errorf("Call() = %+v, want result ok", response)
+		}
+	}
+	if auth != "Basic ZWxlY3RydW06ZWxlY3RydW16" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	want := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "getbalance",
+		"id":      1.0,
+		"xpub":    "xpub1",
+		"params":  map[string]interface{}{"a": 1.0},
+	}
+	if len(requests) != 2 || !reflect.DeepEqual(requests[1], want) {
+		t.Errorf("requests = %v, second one should be %v", requests, want)
+	}
+}
+
+func TestClientUserAgent(t *testing.T) {
+	var userAgent string
+	server := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+		userAgent = r.Header.Get("User-Agent")
+		return http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": request["id"], "result": nil}
+	})
+	defer server.Close()
+	for _, test := range []struct {
+		opts Options
+		want string
+	}{
+		{Options{UserAgent: "bitcart-cli/1.0.0"}, "bitcart-cli/1.0.0"},
+		{Options{UserAgent: "bitcart-cli/1.0.0", Headers: map[string]string{"User-Agent": "proxy-check"}}, "proxy-check"},
+	} {
+		client, err := NewClient(server.URL, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Call("help"); err != nil {
+			t.Fatal(err)
+		}
+		if userAgent != test.want {
+			t.Errorf("User-Agent = %q, want %q", userAgent, test.want)
+		}
+	}
+}
+
+func TestClientCustomAuthorization(t *testing.T) {
+	var auth string
+	server := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+		auth = r.Header.Get("Authorization")
+		return http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": request["id"], "result": nil}
+	})
+	defer server.Close()
+	client, err := NewClient(server.URL, Options{Token: "t", Headers: map[string]string{"Authorization": "Custom"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Call("help"); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Custom" {
+		t.Errorf("Authorization = %q, want Custom", auth)
+	}
+}
+
+func TestClientWithAuth(t *testing.T) {
+	var auths []string
+	server := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+		auths = append(auths, r.Header.Get("Authorization"))
+		return http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": request["id"], "result": "ok"}
+	})
+	defer server.Close()
+	client, err := NewClient(server.URL, Options{User: "electrum", Password: "electrumz", Headers: map[string]string{"X-Tenant": "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenant := client.WithAuth("tenant", "secret", "")
+	for _, c := range []*Client{tenant, client, client.WithAuth("", "", "token")} {
+		if _, err := c.Call("getbalance"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{AuthHeader("tenant", "secret", ""), AuthHeader("electrum", "electrumz", ""), "Bearer token"}
+	if !reflect.DeepEqual(auths, want) {
+		t.Errorf("Authorization headers = %v, want %v", auths, want)
+	}
+	if tenant.Headers["X-Tenant"] != "a" {
+		t.Errorf("WithAuth() headers = %v, want custom headers kept", tenant.Headers)
+	}
+}
+
+func TestClientRPCError(t *testing.T) {
+	calls := 0
+	server := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+		calls++
+		return http.StatusOK, map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      request["id"],
+			"error":   map[string]interface{}{"code": -32601, "message": "Procedure not found."},
+		}
+	})
+	defer server.Close()
+	client, err := NewClient(server.URL, Options{Retries: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := client.Call("nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Error == nil || response.Error.Code != -32601 {
+		t.Errorf("Call() error = %v, want code -32601", response.Error)
+	}
+	if calls != 1 {
+		t.Errorf("JSON-RPC error was retried, %d calls made", calls)
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	calls := 0
+	server := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+		calls++
+		if calls == 1 {
+			return http.StatusInternalServerError, nil
+		}
+		return http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": request["id"], "result": "ok"}
+	})
+	defer server.Close()
+	client, err := NewClient(server.URL, Options{Retries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	retries := 0
+	client.OnRetry = func(_ *jsonrpc.RPCRequest, attempt int, _ time.Duration, _ error) {
+		retries = attempt
+	}
+	response, err := client.Call("getinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Result != "ok" || calls != 2 || retries != 1 {
+		t.Errorf("Call() = %+v after %d calls and %d retries, want ok after 2 calls and 1 retry", response, calls, retries)
+	}
+}
+
+func TestClientNoRetry(t *testing.T) {
+	server := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+		return http.StatusInternalServerError, nil
+	})
+	defer server.Close()
+	client, err := NewClient(server.URL, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Call("getinfo")
+	httpErr, ok := err.(*jsonrpc.HTTPError)
+	if !ok || httpErr.Code != http.StatusInternalServerError {
+		t.Errorf("Call() error = %v, want HTTP 500 error", err)
+	}
+	if !IsTransient(err) {
+		t.Error("IsTransient() = false for HTTP 500 error")
+	}
+}
+
+func TestClientRPCVersion(t *testing.T) {
+	var version interface{}
+	server := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+		version = request["jsonrpc"]
+		return http.StatusOK, map[string]interface{}{"id": request["id"], "result": nil, "error": nil}
+	})
+	defer server.Close()
+	client, err := NewClient(server.URL, Options{RPCVersion: "1.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Call("getinfo"); err != nil {
+		t.Fatal(err)
+	}
+	if version != "1.0" {
+		t.Errorf("jsonrpc = %v, want 1.0", version)
+	}
+	if _, err := NewClient(server.URL, Options{RPCVersion: "3.0"}); err == nil {
+		t.Error("NewClient() accepted unsupported JSON-RPC version")
+	}
+}
+
+func TestClientUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bitcart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "btc.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(testHandler(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": request["id"], "result": r.URL.Path}
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+	client, err := NewClient("unix://"+socket+":/rpc", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := client.Call("getinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Result != "/rpc" {
+		t.Errorf("request path = %v, want /rpc", response.Result)
+	}
+}
+
+func TestClientSendContext(t *testing.T) {
+	server := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+		if request["method"] == "sleep" {
+			time.Sleep(300 * time.Millisecond)
+		}
+		return http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": request["id"], "result": "ok"}
+	})
+	defer server.Close()
+	client, err := NewClient(server.URL, Options{Retries: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.SendContext(ctx, client.newRequest("sleep")); err == nil {
+		t.Error("SendContext() succeeded after deadline")
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("SendContext() returned after %v, want about 50ms", elapsed)
+	}
+	// client keeps working after cancelled call
+	if response, err := client.Call("getinfo"); err != nil || response.Result != "ok" {
+		t.Errorf("Call() = %+v, %v after cancelled call", response, err)
+	}
+}
+
+func TestClientFailover(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	server := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": request["id"], "result": "ok"}
+	})
+	defer server.Close()
+	client, err := NewClient(dead.URL, Options{Fallbacks: []string{server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var switches []string
+	client.OnFailover = func(_ *jsonrpc.RPCRequest, from, to string, err error) {
+		switches = append(switches, from+" -> "+to)
+	}
+	for i := 0; i < 2; i++ {
+		if response, err := client.Call("getinfo"); err != nil || response.Result != "ok" {
+			t.Fatalf("Call() = %+v, %v, want result from fallback", response, err)
+		}
+	}
+	// working fallback is used for the second call right away
+	if want := []string{dead.URL + " -> " + server.URL}; !reflect.DeepEqual(switches, want) {
+		t.Errorf("failovers = %v, want %v", switches, want)
+	}
+}
+
+func TestClientFailoverRPCError(t *testing.T) {
+	calls := 0
+	fallback := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+		calls++
+		return http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": request["id"], "result": "ok"}
+	})
+	defer fallback.Close()
+	server := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+		return http.StatusInternalServerError, nil
+	})
+	defer server.Close()
+	client, err := NewClient(server.URL, Options{Fallbacks: []string{fallback.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Call("getinfo"); err == nil {
+		t.Error("Call() succeeded, want HTTP 500 error of primary daemon")
+	}
+	if calls != 0 {
+		t.Errorf("fallback called %d times after HTTP error, want 0", calls)
+	}
+}
+
+func TestClientSendBatch(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requests []map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
+			t.Errorf("decoding batch: %v", err)
+		}
+		// answers come in reverse order and the last request is left unanswered
+		var responses []map[string]interface{}
+		for i := len(requests) - 2; i >= 0; i-- {
+			methods = append(methods, requests[i]["method"].(string))
+			responses = append(responses, map[string]interface{}{"jsonrpc": "2.0", "id": requests[i]["id"], "result": requests[i]["method"]})
+		}
+		json.NewEncoder(w).Encode(responses)
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := []*jsonrpc.RPCRequest{client.newRequest("getinfo"), client.newRequest("getbalance"), client.newRequest("history")}
+	responses, err := client.SendBatchContext(context.Background(), requests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 3 || responses[0].Result != "getinfo" || responses[1].Result != "getbalance" || responses[2] != nil {
+		t.Errorf("SendBatchContext() = %+v, want results of getinfo and getbalance in order and nil", responses)
+	}
+	if len(methods) != 2 {
+		t.Errorf("daemon answered %v, want one batch of getbalance and getinfo", methods)
+	}
+}
+
+func TestClientSendBatchUnsupported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": nil, "error": map[string]interface{}{"code": -32600, "message": "Invalid Request"}})
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.SendBatchContext(context.Background(), []*jsonrpc.RPCRequest{client.newRequest("getinfo")})
+	if err == nil || !IsBatchRejected(err) {
+		t.Errorf("SendBatchContext() error = %v, want rejected batch for single error object answering it", err)
+	}
+}
+
+func TestIsBatchRejected(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   bool
+	}{
+		{http.StatusBadRequest, `{"error": "batches not supported"}`, true},
+		{http.StatusOK, `{"jsonrpc": "2.0", "id": null, "error": {"code": -32600, "message": "Invalid Request"}}`, true},
+		{http.StatusInternalServerError, `{"error": "boom"}`, false},
+		// daemon may have run the batch before failing to answer
+		{http.StatusOK, `[{"jsonrpc": "2.0", "id": 0, "res`, false},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+		client, err := NewClient(server.URL, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.SendBatchContext(context.Background(), []*jsonrpc.RPCRequest{client.newRequest("getinfo")})
+		if err == nil {
+			t.Errorf("SendBatchContext() with HTTP %d %s succeeded", tt.status, tt.body)
+		} else if got := IsBatchRejected(err); got != tt.want {
+			t.Errorf("IsBatchRejected(%v) = %v, want %v", err, got, tt.want)
+		}
+		server.Close()
+	}
+
+	// connection dropped after daemon read the batch
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SendBatchContext(context.Background(), []*jsonrpc.RPCRequest{client.newRequest("getinfo")}); err == nil || IsBatchRejected(err) {
+		t.Errorf("SendBatchContext() error = %v after dropped connection, want error which isn't rejected batch", err)
+	}
+}
@@ -0,0 +1,68 @@
+package bitcart
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// SortedCoins returns coin names in alphabetical order
+func SortedCoins(coins map[string]string) []string {
+	names := make([]string, 0, len(coins))
+	for name := range coins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RewriteURL replaces host and/or port of daemon URL, keeping the rest.
+// host may include scheme, e.g. https://10.0.0.5
+func RewriteURL(rawURL string, host string, port string) (string, error) {
+	// Unix socket URLs have no host to rewrite
+	if _, _, ok := UnixSocketURL(rawURL); ok {
+		return rawURL, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if host != "" {
+		if i := strings.Index(host, "://"); i >= 0 {
+			u.Scheme, host = host[:i], host[i+3:]
+		}
+		if u.Port() != "" {
+			u.Host = net.JoinHostPort(host, u.Port())
+		} else {
+			u.Host = host
+		}
+	}
+	if port != "" {
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return u.String(), nil
+}
+
+// EnvCoinURLs returns coin URLs from BITCART_<COIN>_URL environment variables
+func EnvCoinURLs(environ []string) map[string]string {
+	result := map[string]string{}
+	for _, item := range environ {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			continue
+		}
+		name := parts[0]
+		// prefix and suffix must not overlap, BITCART_URL names no coin
+		if len(name) <= len("BITCART__URL") || !strings.HasPrefix(name, "BITCART_") || !strings.HasSuffix(name, "_URL") {
+			continue
+		}
+		coin := name[len("BITCART_") : len(name)-len("_URL")]
+		// BITCART_DAEMON_URL is --url flag
+		if coin == "DAEMON" {
+			continue
+		}
+		result[strings.ToLower(coin)] = parts[1]
+	}
+	return result
+}
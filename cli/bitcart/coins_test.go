@@ -0,0 +1,54 @@
+package bitcart
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortedCoins(t *testing.T) {
+	coins := map[string]string{"ltc": "", "btc": "", "gzro": ""}
+	want := []string{"btc", "gzro", "ltc"}
+	if got := SortedCoins(coins); !reflect.DeepEqual(got, want) {
+		t.Errorf("SortedCoins() = %v, want %v", got, want)
+	}
+}
+
+func TestRewriteURL(t *testing.T) {
+	tests := []struct {
+		url, host, port string
+		want            string
+	}{
+		{"http://localhost:5000", "10.0.0.5", "", "http://10.0.0.5:5000"},
+		{"http://localhost:5000", "https://node.example", "", "https://node.example:5000"},
+		{"http://localhost:5000", "", "6000", "http://localhost:6000"},
+		{"http://localhost:5000/rpc", "node", "6000", "http://node:6000/rpc"},
+		{"https://daemon.example", "node", "", "https://node"},
+		{"http://localhost:5000", "::1", "", "http://[::1]:5000"},
+		{"unix:///run/btc.sock:/", "node", "6000", "unix:///run/btc.sock:/"},
+	}
+	for _, test := range tests {
+		got, err := RewriteURL(test.url, test.host, test.port)
+		if err != nil {
+			t.Errorf("RewriteURL(%q, %q, %q) failed: %v", test.url, test.host, test.port, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("RewriteURL(%q, %q, %q) = %q, want %q", test.url, test.host, test.port, got, test.want)
+		}
+	}
+}
+
+func TestEnvCoinURLs(t *testing.T) {
+	environ := []string{
+		"BITCART_BTC_URL=http://btc:5000",
+		"BITCART_LTC_URL=",
+		"BITCART_DAEMON_URL=http://daemon",
+		"BITCART_URL=http://nothing",
+		"BITCART_BCH_HOST=bch",
+		"HOME=/root",
+	}
+	want := map[string]string{"btc": "http://btc:5000"}
+	if got := EnvCoinURLs(environ); !reflect.DeepEqual(got, want) {
+		t.Errorf("EnvCoinURLs() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,24 @@
+package bitcart
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Marshal encodes value as JSON without HTML escaping, indented unless compact.
+// Raw JSON is returned untouched
+func Marshal(value interface{}, compact bool) ([]byte, error) {
+	if raw, ok := value.(json.RawMessage); ok {
+		return raw, nil
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if !compact {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
@@ -0,0 +1,30 @@
+package bitcart
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	value := map[string]interface{}{"a": []int{1}, "b": "<x>"}
+	tests := []struct {
+		compact bool
+		want    string
+	}{
+		{true, `{"a":[1],"b":"<x>"}`},
+		{false, "{\n  \"a\": [\n    1\n  ],\n  \"b\": \"<x>\"\n}"},
+	}
+	for _, test := range tests {
+		got, err := Marshal(value, test.compact)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("Marshal(compact=%v) = %q, want %q", test.compact, got, test.want)
+		}
+	}
+	raw := json.RawMessage(`{"b": 1,  "a": 2}`)
+	if got, _ := Marshal(raw, false); string(got) != string(raw) {
+		t.Errorf("Marshal(raw) = %q, want it untouched", got)
+	}
+}
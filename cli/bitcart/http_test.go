@@ -0,0 +1,183 @@
+package bitcart
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseProxy(t *testing.T) {
+	if _, err := parseProxy("socks5://127.0.0.1:9050"); err != nil {
+		t.Errorf("parseProxy() failed: %v", err)
+	}
+	for _, proxy := range []string{"http://127.0.0.1:8080", "socks5://", "::"} {
+		if _, err := parseProxy(proxy); err == nil {
+			t.Errorf("parseProxy(%q) succeeded, want error", proxy)
+		}
+	}
+}
+
+func TestUnixSocketURL(t *testing.T) {
+	tests := []struct {
+		url, socket, httpURL string
+		ok                   bool
+	}{
+		{"unix:///var/run/bitcart/btc.sock:/", "/var/run/bitcart/btc.sock", "http://localhost/", true},
+		{"unix:///run/btc.sock:/rpc", "/run/btc.sock", "http://localhost/rpc", true},
+		{"unix:///run/btc.sock", "/run/btc.sock", "http://localhost/", true},
+		{"http://localhost:5000", "", "", false},
+	}
+	for _, test := range tests {
+		socket, httpURL, ok := UnixSocketURL(test.url)
+		if socket != test.socket || httpURL != test.httpURL || ok != test.ok {
+			t.Errorf("UnixSocketURL(%q) = %q, %q, %v, want %q, %q, %v", test.url, socket, httpURL, ok, test.socket, test.httpURL, test.ok)
+		}
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	client, err := NewHTTPClient(HTTPOptions{Timeout: time.Second, Insecure: true, Proxy: "socks5://127.0.0.1:9050"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Timeout != time.Second {
+		t.Errorf("Timeout = %v, want 1s", client.Timeout)
+	}
+	transport := client.Transport.(*gzipTransport).transport
+	if !transport.DisableKeepAlives {
+		t.Error("keep-alives are enabled without KeepAlive option")
+	}
+	if !transport.TLSClientConfig.InsecureSkipVerify {
+		t.Error("InsecureSkipVerify is not set")
+	}
+	if transport.Proxy == nil {
+		t.Error("proxy is not set")
+	}
+	client, err = NewHTTPClient(HTTPOptions{KeepAlive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Transport.(*gzipTransport).transport.DisableKeepAlives {
+		t.Error("keep-alives are disabled with KeepAlive option")
+	}
+	if _, err := NewHTTPClient(HTTPOptions{Proxy: "http://proxy"}); err == nil {
+		t.Error("NewHTTPClient() accepted non-SOCKS5 proxy")
+	}
+	if _, err := NewHTTPClient(HTTPOptions{Socket: "/nonexistent/btc.sock"}); err == nil {
+		t.Error("NewHTTPClient() accepted missing socket")
+	}
+}
+
+func TestNewHTTPClientIPVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	// test server listens on 127.0.0.1
+	for version, ok := range map[int]bool{4: true, 6: false} {
+		client, err := NewHTTPClient(HTTPOptions{IPVersion: version})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if (err == nil) != ok {
+			t.Errorf("IPv%d request error = %v, want success %v", version, err, ok)
+		}
+	}
+	if _, err := NewHTTPClient(HTTPOptions{IPVersion: 5}); err == nil {
+		t.Error("NewHTTPClient() accepted IP version 5")
+	}
+}
+
+func TestNewHTTPClientClientCert(t *testing.T) {
+	for _, opts := range []HTTPOptions{
+		{ClientCert: "client.pem"},
+		{ClientKey: "client.key"},
+		{ClientCert: "missing.pem", ClientKey: "missing.key"},
+	} {
+		if _, err := NewHTTPClient(opts); err == nil {
+			t.Errorf("NewHTTPClient(%+v) succeeded, want error", opts)
+		}
+	}
+}
+
+func TestNewHTTPClientCACerts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bitcart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ca.pem")
+	if err := ioutil.WriteFile(path, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, paths := range [][]string{{path}, {filepath.Join(dir, "missing.pem")}} {
+		if _, err := NewHTTPClient(HTTPOptions{CACerts: paths}); err == nil {
+			t.Errorf("NewHTTPClient() with CA certificates %v succeeded, want error", paths)
+		}
+	}
+}
+
+func TestNewHTTPClientHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":"ok"}`))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	for _, keepAlive := range []bool{false, true} {
+		client, err := NewHTTPClient(HTTPOptions{Insecure: true, KeepAlive: keepAlive})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.ProtoMajor != 2 {
+			t.Errorf("protocol with keep-alive %v = %s, want HTTP/2", keepAlive, resp.Proto)
+		}
+	}
+}
+
+func TestGzipResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("Accept-Encoding = %q, want gzip", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		writer.Write([]byte(`{"result":"ok"}`))
+		writer.Close()
+	}))
+	defer server.Close()
+	client, err := NewHTTPClient(HTTPOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, encoding := range []string{"", "gzip"} {
+		req, _ := http.NewRequest("POST", server.URL, nil)
+		if encoding != "" {
+			req.Header.Set("Accept-Encoding", encoding)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != `{"result":"ok"}` {
+			t.Errorf("body = %q with Accept-Encoding %q, want decompressed JSON", body, encoding)
+		}
+	}
+}
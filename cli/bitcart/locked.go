@@ -0,0 +1,28 @@
+package bitcart
+
+import (
+	"strings"
+
+	"github.com/MrNaif2018/jsonrpc"
+)
+
+// WalletLockedCode is JSON-RPC error code of bitcoind-style daemons asking for wallet passphrase
+const WalletLockedCode = -13
+
+// walletLockedMessages are whole error messages of daemons refusing calls to locked wallets, lowercased
+var walletLockedMessages = []string{"wallet is locked", "wallet locked", "password required"}
+
+// IsWalletLocked reports whether JSON-RPC error means wallet has to be unlocked before the call.
+// Only exact messages are matched, so errors merely mentioning locks or passwords don't cause unlocking
+func IsWalletLocked(err *jsonrpc.RPCError) bool {
+	if err.Code == WalletLockedCode {
+		return true
+	}
+	message := strings.ToLower(strings.TrimRight(strings.TrimSpace(err.Message), ".!"))
+	for _, locked := range walletLockedMessages {
+		if message == locked {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package bitcart
+
+import (
+	"testing"
+
+	"github.com/MrNaif2018/jsonrpc"
+)
+
+func TestIsWalletLocked(t *testing.T) {
+	tests := []struct {
+		code    int
+		message string
+		want    bool
+	}{
+		{WalletLockedCode, "Error: Please enter the wallet passphrase with walletpassphrase first.", true},
+		{-32000, "Wallet is locked", true},
+		{-32000, "wallet is locked.", true},
+		{-32000, "Password required", true},
+		{-32000, "wallet is unlocked", false},
+		{-32000, "address is blocked", false},
+		{-32000, "invalid passphrase length", false},
+		{-32000, "password required for encrypted files, pass encrypt_file", false},
+		{-32601, "Procedure not found.", false},
+	}
+	for _, test := range tests {
+		err := &jsonrpc.RPCError{Code: test.code, Message: test.message}
+		if got := IsWalletLocked(err); got != test.want {
+			t.Errorf("IsWalletLocked(%d, %q) = %v, want %v", test.code, test.message, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package bitcart
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/MrNaif2018/jsonrpc"
+)
+
+// Notify sends method call as JSON-RPC notification, which has no id, so daemon doesn't respond with result.
+// Response body is not read, only HTTP error statuses are returned as StatusError.
+// Notifications are not retried or sent to fallbacks, as it is unknown whether they were handled
+func (c *Client) Notify(ctx context.Context, method string, params ...interface{}) error {
+	notification := map[string]interface{}{
+		"jsonrpc": c.RPCVersion,
+		"method":  method,
+		"xpub":    c.Wallet,
+	}
+	if p := jsonrpc.Params(params...); p != nil {
+		notification["params"] = p
+	}
+	// JSON-RPC 1.0 notifications have null id instead of none
+	if c.RPCVersion == "1.0" {
+		notification["id"] = nil
+	}
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	httpResponse, err := c.post(ctx, body)
+	if err != nil {
+		return fmt.Errorf("rpc notification %v() on %v: %v", method, c.URL, err)
+	}
+	defer httpResponse.Body.Close()
+	return checkStatus(httpResponse)
+}
@@ -0,0 +1,50 @@
+package bitcart
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestClientNotify(t *testing.T) {
+	for _, version := range []string{"2.0", "1.0"} {
+		var got map[string]interface{}
+		server := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+			got = request
+			return http.StatusOK, nil
+		})
+		client, err := NewClient(server.URL, Options{Wallet: "xpub", RPCVersion: version})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = client.Notify(context.Background(), "broadcast", "tx")
+		server.Close()
+		if err != nil {
+			t.Errorf("Notify() over JSON-RPC %s failed: %v", version, err)
+			continue
+		}
+		want := map[string]interface{}{"jsonrpc": version, "method": "broadcast", "params": []interface{}{"tx"}, "xpub": "xpub"}
+		if version == "1.0" {
+			want["id"] = nil
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Notify() over JSON-RPC %s sent %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestClientNotifyStatusError(t *testing.T) {
+	server := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+		return http.StatusUnauthorized, "Unauthorized"
+	})
+	defer server.Close()
+	client, err := NewClient(server.URL, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.Notify(context.Background(), "broadcast")
+	if statusErr, ok := err.(*StatusError); !ok || statusErr.Code != http.StatusUnauthorized {
+		t.Errorf("Notify() error = %v, want HTTP 401 status error", err)
+	}
+}
@@ -0,0 +1,95 @@
+package bitcart
+
+// Source is origin of coin URL, URLs from sources with higher values take precedence
+type Source int
+
+// URL sources in order of increasing precedence
+const (
+	SourceDefault Source = iota
+	// SourceFile is standalone coin file shared by tools, without credentials
+	SourceFile
+	SourceConfig
+	SourceEnv
+	SourceFlag
+)
+
+// DefaultCoins are coins known without configuration and their default daemon URLs
+var DefaultCoins = map[string]string{
+	"btc":  "http://localhost:5000",
+	"ltc":  "http://localhost:5001",
+	"gzro": "http://localhost:5002",
+}
+
+// Registry resolves daemon URLs of coins from defaults, coin file, config file, environment and flags.
+// Each coin resolves to URL from its source with the highest precedence, regardless of order URLs were set in
+type Registry struct {
+	urls map[string]map[Source]string
+}
+
+// NewRegistry returns registry with DefaultCoins registered
+func NewRegistry() *Registry {
+	r := &Registry{urls: map[string]map[Source]string{}}
+	for name, url := range DefaultCoins {
+		r.Register(name, url)
+	}
+	return r
+}
+
+// Register adds coin with default daemon URL
+func (r *Registry) Register(name, defaultURL string) {
+	r.Set(name, defaultURL, SourceDefault)
+}
+
+// Set sets URL of coin from source, adding the coin if it is unknown
+func (r *Registry) Set(name, url string, source Source) {
+	if r.urls[name] == nil {
+		r.urls[name] = map[Source]string{}
+	}
+	r.urls[name][source] = url
+}
+
+// Resolve returns URL of coin, ok is false if coin is unknown
+func (r *Registry) Resolve(name string) (url string, ok bool) {
+	url, _, ok = r.resolve(name)
+	return url, ok
+}
+
+// resolve returns URL of coin with its source
+func (r *Registry) resolve(name string) (string, Source, bool) {
+	best, found := SourceDefault, false
+	for source := range r.urls[name] {
+		if !found || source > best {
+			best, found = source, true
+		}
+	}
+	return r.urls[name][best], best, found
+}
+
+// Source returns source of resolved URL of coin
+func (r *Registry) Source(name string) Source {
+	_, source, _ := r.resolve(name)
+	return source
+}
+
+// Rewrite replaces each URL set so far with result of fn, e.g. to change host of all coins
+func (r *Registry) Rewrite(fn func(name, url string) (string, error)) error {
+	for name, urls := range r.urls {
+		for source, url := range urls {
+			rewritten, err := fn(name, url)
+			if err != nil {
+				return err
+			}
+			urls[source] = rewritten
+		}
+	}
+	return nil
+}
+
+// Coins returns resolved URLs of all coins
+func (r *Registry) Coins() map[string]string {
+	coins := make(map[string]string, len(r.urls))
+	for name := range r.urls {
+		coins[name], _ = r.Resolve(name)
+	}
+	return coins
+}
@@ -0,0 +1,74 @@
+package bitcart
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegistryDefaults(t *testing.T) {
+	r := NewRegistry()
+	if got := r.Coins(); !reflect.DeepEqual(got, DefaultCoins) {
+		t.Errorf("Coins() = %v, want %v", got, DefaultCoins)
+	}
+	if _, ok := r.Resolve("doge"); ok {
+		t.Error("Resolve() found unregistered coin")
+	}
+	r.Register("doge", "http://localhost:5004")
+	if url, ok := r.Resolve("doge"); !ok || url != "http://localhost:5004" {
+		t.Errorf("Resolve(doge) = %q, %v, want registered default", url, ok)
+	}
+}
+
+func TestRegistryPrecedence(t *testing.T) {
+	r := NewRegistry()
+	// sources are set out of order, precedence doesn't depend on it
+	r.Set("btc", "http://flag", SourceFlag)
+	r.Set("btc", "http://env", SourceEnv)
+	r.Set("btc", "http://config", SourceConfig)
+	r.Set("ltc", "http://config", SourceConfig)
+	r.Set("ltc", "http://env", SourceEnv)
+	r.Set("gzro", "http://config", SourceConfig)
+	r.Set("gzro", "http://file", SourceFile)
+	r.Set("doge", "http://file", SourceFile)
+	// coins only known from config are added
+	r.Set("bch", "http://config", SourceConfig)
+	tests := []struct {
+		coin   string
+		url    string
+		source Source
+	}{
+		{"btc", "http://flag", SourceFlag},
+		{"ltc", "http://env", SourceEnv},
+		{"gzro", "http://config", SourceConfig},
+		{"bch", "http://config", SourceConfig},
+		{"doge", "http://file", SourceFile},
+	}
+	for _, test := range tests {
+		url, ok := r.Resolve(test.coin)
+		if !ok || url != test.url || r.Source(test.coin) != test.source {
+			t.Errorf("Resolve(%s) = %q from source %d, want %q from source %d", test.coin, url, r.Source(test.coin), test.url, test.source)
+		}
+	}
+}
+
+func TestRegistryRewrite(t *testing.T) {
+	r := NewRegistry()
+	r.Set("btc", "http://localhost:6000", SourceEnv)
+	err := r.Rewrite(func(name, url string) (string, error) {
+		return strings.Replace(url, "localhost", "node", 1), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// URLs set after rewrite are kept as given
+	r.Set("ltc", "http://localhost:7000", SourceFlag)
+	want := map[string]string{
+		"btc":  "http://node:6000",
+		"ltc":  "http://localhost:7000",
+		"gzro": "http://node:5002",
+	}
+	if got := r.Coins(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Coins() = %v, want %v", got, want)
+	}
+}
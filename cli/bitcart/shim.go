@@ -0,0 +1,124 @@
+package bitcart
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Shim maps friendly method invocation to daemon method. Args name arguments of the invocation,
+// given positionally or by name, and Params is template of daemon params with JSON structure,
+// where strings "$name" are replaced by argument values. Arguments which aren't given take values
+// from Defaults. Without Params the arguments are sent positionally in Args order
+type Shim struct {
+	Method   string
+	Args     []string
+	Params   interface{}
+	Defaults map[string]interface{}
+}
+
+// Apply returns daemon params for params of invocation name of the shim, a list of positional
+// or object of named arguments. Object keys of missing arguments are left out of params,
+// as are trailing list elements, missing arguments before given ones are an error
+func (sh Shim) Apply(name string, params interface{}) (interface{}, error) {
+	values, err := sh.bind(name, params)
+	if err != nil {
+		return nil, err
+	}
+	template := sh.Params
+	if template == nil {
+		placeholders := make([]interface{}, len(sh.Args))
+		for i, arg := range sh.Args {
+			placeholders[i] = "$" + arg
+		}
+		template = placeholders
+	}
+	declared := make(map[string]bool, len(sh.Args))
+	for _, arg := range sh.Args {
+		declared[arg] = true
+	}
+	filled, _, err := fillTemplate(template, values, declared)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return filled, nil
+}
+
+// bind returns argument values of invocation by name, with defaults of arguments not given
+func (sh Shim) bind(name string, params interface{}) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	switch params := params.(type) {
+	case []interface{}:
+		if len(params) > len(sh.Args) {
+			return nil, fmt.Errorf("%s takes at most %d arguments: %s", name, len(sh.Args), strings.Join(sh.Args, ", "))
+		}
+		for i, value := range params {
+			values[sh.Args[i]] = value
+		}
+	case map[string]interface{}:
+		for arg, value := range params {
+			found := false
+			for _, declared := range sh.Args {
+				found = found || declared == arg
+			}
+			if !found {
+				return nil, fmt.Errorf("%s has no argument %q, arguments are: %s", name, arg, strings.Join(sh.Args, ", "))
+			}
+			values[arg] = value
+		}
+	case nil:
+	default:
+		return nil, fmt.Errorf("%s needs list or object of arguments, got %T", name, params)
+	}
+	for arg, value := range sh.Defaults {
+		if _, ok := values[arg]; !ok {
+			values[arg] = value
+		}
+	}
+	return values, nil
+}
+
+// fillTemplate replaces "$name" strings of declared arguments in template by their values,
+// reporting whether value is present
+func fillTemplate(template interface{}, values map[string]interface{}, declared map[string]bool) (interface{}, bool, error) {
+	switch t := template.(type) {
+	case string:
+		if name := strings.TrimPrefix(t, "$"); name != t && declared[name] {
+			value, ok := values[name]
+			return value, ok, nil
+		}
+		return t, true, nil
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(t))
+		for key, item := range t {
+			value, ok, err := fillTemplate(item, values, declared)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				result[key] = value
+			}
+		}
+		return result, true, nil
+	case []interface{}:
+		result := make([]interface{}, 0, len(t))
+		missing := ""
+		for _, item := range t {
+			value, ok, err := fillTemplate(item, values, declared)
+			if err != nil {
+				return nil, false, err
+			}
+			if !ok {
+				if missing == "" {
+					missing = item.(string)[1:]
+				}
+				continue
+			}
+			if missing != "" {
+				return nil, false, fmt.Errorf("argument %s is missing", missing)
+			}
+			result = append(result, value)
+		}
+		return result, true, nil
+	}
+	return template, true, nil
+}
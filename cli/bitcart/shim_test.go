@@ -0,0 +1,51 @@
+package bitcart
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShimApply(t *testing.T) {
+	send := Shim{Method: "payto", Args: []string{"to", "amount"}, Params: []interface{}{"$amount", "$to"}}
+	named := Shim{
+		Method:   "payto",
+		Args:     []string{"to", "amount", "fee"},
+		Params:   map[string]interface{}{"destination": "$to", "amount": "$amount", "fee": "$fee", "unsigned": false, "note": "$other"},
+		Defaults: map[string]interface{}{"fee": 0.0001},
+	}
+	positional := Shim{Method: "getbalance", Args: []string{"wallet", "confirmed"}}
+	tests := []struct {
+		name   string
+		shim   Shim
+		params interface{}
+		want   interface{}
+		err    string
+	}{
+		{"positional args reordered", send, []interface{}{"addr", 1.5}, []interface{}{1.5, "addr"}, ""},
+		{"named args", send, map[string]interface{}{"amount": 2, "to": "addr"}, []interface{}{2, "addr"}, ""},
+		{"missing trailing arg", send, []interface{}{}, []interface{}{}, ""},
+		{"missing arg before given", send, []interface{}{"addr"}, nil, "send: argument amount is missing"},
+		{"too many args", send, []interface{}{"a", 1, 2}, nil, "send takes at most 2 arguments: to, amount"},
+		{"unknown named arg", send, map[string]interface{}{"x": 1}, nil, `send has no argument "x", arguments are: to, amount`},
+		{"default and literal values", named, []interface{}{"addr", 1}, map[string]interface{}{"destination": "addr", "amount": 1, "fee": 0.0001, "unsigned": false, "note": "$other"}, ""},
+		{"given arg overrides default", named, map[string]interface{}{"to": "addr", "fee": 0}, map[string]interface{}{"destination": "addr", "fee": 0, "unsigned": false, "note": "$other"}, ""},
+		{"no params template", positional, []interface{}{"main"}, []interface{}{"main"}, ""},
+		{"no args", positional, nil, []interface{}{}, ""},
+	}
+	for _, test := range tests {
+		got, err := test.shim.Apply("send", test.params)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: error = %v, want %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Apply() = %#v, want %#v", test.name, got, test.want)
+		}
+	}
+}
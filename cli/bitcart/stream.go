@@ -0,0 +1,187 @@
+package bitcart
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/MrNaif2018/jsonrpc"
+)
+
+// maxStatusBody is how many bytes of error response body are kept in StatusError
+const maxStatusBody = 200
+
+// StatusError is returned by Stream when daemon responds with HTTP error status
+type StatusError struct {
+	Code   int
+	Status string
+	// Body is the beginning of response body
+	Body []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("daemon returned HTTP %s: %s", e.Status, bytes.TrimSpace(e.Body))
+}
+
+// PartialResultError is returned by Stream when response breaks off or can't be decoded after it started.
+// Elements passed to each before that are complete
+type PartialResultError struct {
+	// Elements is how many array elements were passed to each
+	Elements int
+	Err      error
+}
+
+func (e *PartialResultError) Error() string {
+	return fmt.Sprintf("response ended after %d elements: %v", e.Elements, e.Err)
+}
+
+func (e *PartialResultError) Unwrap() error {
+	return e.Err
+}
+
+// Stream sends request and calls each for every element of array result as it is decoded,
+// so large results are never held in memory. Results which aren't arrays are returned in response.
+// JSON-RPC errors are returned in response. Streamed requests are not retried or sent to fallbacks
+func (c *Client) Stream(request *jsonrpc.RPCRequest, each func(element json.RawMessage) error) (*jsonrpc.RPCResponse, error) {
+	return c.StreamContext(context.Background(), request, each)
+}
+
+// StreamContext is like Stream, but the request is cancelled with ctx
+func (c *Client) StreamContext(ctx context.Context, request *jsonrpc.RPCRequest, each func(element json.RawMessage) error) (*jsonrpc.RPCResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	httpResponse, err := c.post(ctx, body)
+	if err != nil {
+		return nil, fmt.Errorf("rpc call %v() on %v: %v", request.Method, c.URL, err)
+	}
+	defer httpResponse.Body.Close()
+	if err := checkStatus(httpResponse); err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(httpResponse.Body)
+	decoder.UseNumber()
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("rpc call %v() on %v: response is not a JSON-RPC object", request.Method, c.URL)
+	}
+	response := &jsonrpc.RPCResponse{}
+	elements := 0
+	var eachErr error
+	count := func(element json.RawMessage) error {
+		if eachErr = each(element); eachErr == nil {
+			elements++
+		}
+		return eachErr
+	}
+	if err := decodeResponse(decoder, response, count); err != nil {
+		if err == eachErr {
+			return nil, err
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, &PartialResultError{Elements: elements, Err: err}
+	}
+	response.ID = request.ID
+	return response, nil
+}
+
+// post sends JSON body to daemon with client headers
+func (c *Client) post(ctx context.Context, body []byte) (*http.Response, error) {
+	httpRequest, err := http.NewRequest("POST", c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest = httpRequest.WithContext(ctx)
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("Accept", "application/json")
+	for name, value := range c.Headers {
+		httpRequest.Header.Set(name, value)
+	}
+	return c.httpClient.Do(httpRequest)
+}
+
+// checkStatus returns StatusError for HTTP error response
+func checkStatus(httpResponse *http.Response) error {
+	if httpResponse.StatusCode < 400 {
+		return nil
+	}
+	data, _ := ioutil.ReadAll(io.LimitReader(httpResponse.Body, maxStatusBody))
+	return &StatusError{Code: httpResponse.StatusCode, Status: httpResponse.Status, Body: data}
+}
+
+// decodeResponse reads fields of response object after its opening brace, up to the closing one
+func decodeResponse(decoder *json.Decoder, response *jsonrpc.RPCResponse, each func(element json.RawMessage) error) error {
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case "result":
+			response.Result, err = streamValue(decoder, each)
+		case "error":
+			err = decoder.Decode(&response.Error)
+		case "jsonrpc":
+			err = decoder.Decode(&response.JSONRPC)
+		default:
+			var skipped json.RawMessage
+			err = decoder.Decode(&skipped)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// More is false on EOF too, so missing end of object means response was cut off
+	_, err := decoder.Token()
+	return err
+}
+
+// streamValue reads next value from decoder, passing array elements to each one by one.
+// Other values are returned
+func streamValue(decoder *json.Decoder, each func(element json.RawMessage) error) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('[') {
+		return tokenValue(decoder, token)
+	}
+	for decoder.More() {
+		var element json.RawMessage
+		if err := decoder.Decode(&element); err != nil {
+			return nil, err
+		}
+		if err := each(element); err != nil {
+			return nil, err
+		}
+	}
+	_, err = decoder.Token()
+	return nil, err
+}
+
+// tokenValue builds non-array value starting with already read token
+func tokenValue(decoder *json.Decoder, token json.Token) (interface{}, error) {
+	if token == json.Delim('{') {
+		object := map[string]interface{}{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return nil, err
+			}
+			object[key.(string)] = value
+		}
+		_, err := decoder.Token()
+		return object, err
+	}
+	return token, nil
+}
@@ -0,0 +1,112 @@
+package bitcart
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClientStream(t *testing.T) {
+	tests := []struct {
+		response map[string]interface{}
+		elements []string
+		result   interface{}
+		code     int
+	}{
+		{
+			response: map[string]interface{}{"result": []interface{}{map[string]interface{}{"txid": "aa"}, 2, "x"}},
+			elements: []string{`{"txid":"aa"}`, `2`, `"x"`},
+		},
+		{
+			response: map[string]interface{}{"result": map[string]interface{}{"confirmed": "1.5", "list": []int{1}}},
+			result:   map[string]interface{}{"confirmed": "1.5", "list": []interface{}{json.Number("1")}},
+		},
+		{
+			response: map[string]interface{}{"result": "ok"},
+			result:   "ok",
+		},
+		{
+			response: map[string]interface{}{"result": nil, "error": map[string]interface{}{"code": -32601, "message": "Procedure not found."}},
+			code:     -32601,
+		},
+	}
+	for _, test := range tests {
+		server := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+			test.response["jsonrpc"] = "2.0"
+			test.response["id"] = request["id"]
+			return http.StatusOK, test.response
+		})
+		client, err := NewClient(server.URL, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var elements []string
+		response, err := client.Stream(client.newRequest("history"), func(element json.RawMessage) error {
+			elements = append(elements, string(element))
+			return nil
+		})
+		server.Close()
+		if err != nil {
+			t.Errorf("Stream(%v) failed: %v", test.response, err)
+			continue
+		}
+		if !reflect.DeepEqual(elements, test.elements) {
+			t.Errorf("Stream(%v) elements = %v, want %v", test.response, elements, test.elements)
+		}
+		if !reflect.DeepEqual(response.Result, test.result) {
+			t.Errorf("Stream(%v) result = %#v, want %#v", test.response, response.Result, test.result)
+		}
+		if test.code != 0 && (response.Error == nil || response.Error.Code != test.code) {
+			t.Errorf("Stream(%v) error = %v, want code %d", test.response, response.Error, test.code)
+		}
+	}
+}
+
+func TestClientStreamStatusError(t *testing.T) {
+	server := testDaemon(t, func(request map[string]interface{}, r *http.Request) (int, interface{}) {
+		return http.StatusUnauthorized, "Unauthorized"
+	})
+	defer server.Close()
+	client, err := NewClient(server.URL, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Stream(client.newRequest("history"), func(json.RawMessage) error { return nil })
+	statusErr, ok := err.(*StatusError)
+	if !ok || statusErr.Code != http.StatusUnauthorized || statusErr.Status != "401 Unauthorized" {
+		t.Errorf("Stream() error = %v, want HTTP 401 status error", err)
+	}
+}
+
+func TestClientStreamTruncated(t *testing.T) {
+	tests := []struct {
+		body     string
+		elements int
+	}{
+		{`{"jsonrpc":"2.0","id":0,"result":[{"txid":"aa"},{"txid":"bb"},{"tx`, 2},
+		{`{"jsonrpc":"2.0","id":0,"result":[1,2`, 2},
+		{`{"jsonrpc":"2.0","id":0,"result":[1]`, 1},
+		{`{"jsonrpc":"2.0","id":0,"result":[1,}`, 1},
+	}
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(test.body))
+		}))
+		client, err := NewClient(server.URL, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var elements []string
+		_, err = client.Stream(client.newRequest("history"), func(element json.RawMessage) error {
+			elements = append(elements, string(element))
+			return nil
+		})
+		server.Close()
+		partial, ok := err.(*PartialResultError)
+		if !ok || partial.Elements != test.elements || len(elements) != test.elements {
+			t.Errorf("Stream() of %s error = %v after %d elements, want partial result error after %d", test.body, err, len(elements), test.elements)
+		}
+	}
+}
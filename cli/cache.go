@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/MrNaif2018/jsonrpc"
+)
+
+// defaultCacheMethods are patterns of read-only methods cached with --cache,
+// unless cache_methods is set in config file
+var defaultCacheMethods = []string{"get*", "list*", "help", "version", "history", "validateaddress"}
+
+// responseCache keeps successful responses of read-only methods for ttl
+type responseCache struct {
+	ttl      time.Duration
+	patterns []string
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+}
+
+// cacheEntry is cached response with time it was received
+type cacheEntry struct {
+	response *jsonrpc.RPCResponse
+	stored   time.Time
+}
+
+// newResponseCache creates cache of methods matching patterns, nil if ttl is 0
+func newResponseCache(ttl time.Duration, patterns []string) *responseCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &responseCache{ttl: ttl, patterns: patterns, entries: map[string]cacheEntry{}}
+}
+
+// key returns cache key of call, ok is false if method isn't cached
+func (rc *responseCache) key(coin, wallet, method string, params interface{}) (string, bool) {
+	if rc == nil || !matchMethod(method, rc.patterns) {
+		return "", false
+	}
+	b, err := json.Marshal(params)
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%s", coin, wallet, method, b), true
+}
+
+// get returns unexpired response stored under key
+func (rc *responseCache) get(key string) (*jsonrpc.RPCResponse, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry, ok := rc.entries[key]
+	if !ok || time.Since(entry.stored) >= rc.ttl {
+		delete(rc.entries, key)
+		return nil, false
+	}
+	return entry.response, true
+}
+
+// put stores response under key, JSON-RPC errors are not cached
+func (rc *responseCache) put(key string, response *jsonrpc.RPCResponse) {
+	if response.Error != nil {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.entries[key] = cacheEntry{response: response, stored: time.Now()}
+}
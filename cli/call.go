@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+	"github.com/MrNaif2018/jsonrpc"
+	"github.com/urfave/cli"
+)
+
+// maxErrorBody is how many bytes of non-JSON-RPC response body are shown in errors
+const maxErrorBody = 200
+
+// docsURL is Bitcart documentation, referred to in error messages
+const docsURL = "https://docs.bitcartcc.com/en/latest/"
+
+// rpcError is JSON-RPC error printed with request id for correlation
+type rpcError struct {
+	*jsonrpc.RPCError
+	ID int `json:"id"`
+}
+
+// rawResponse holds undecoded parts of JSON-RPC response
+type rawResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// newRequest returns request for method with session wallet and next request id
+func (s *session) newRequest(method string, params ...interface{}) *jsonrpc.RPCRequest {
+	s.lastID = s.nextID
+	s.nextID++
+	if s.verbose > 0 {
+		fmt.Fprintf(stderr, "* request id: %d\n", s.lastID)
+	}
+	return &jsonrpc.RPCRequest{
+		Method:  method,
+		Params:  jsonrpc.Params(params...),
+		ID:      s.lastID,
+		JSONRPC: s.client.RPCVersion,
+		Xpub:    s.wallet,
+	}
+}
+
+// do calls RPC method with session wallet and next request id.
+// Transient failures are retried by client, JSON-RPC errors are returned in response
+// Results of read-only methods are served from cache with --cache, locked wallets are unlocked with --unlock-password
+func (s *session) do(method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	request := s.newRequest(method, params...)
+	key, cached := s.cache.key(s.coin, s.wallet, method, request.Params)
+	if cached {
+		if result, ok := s.cache.get(key); ok {
+			if s.verbose > 0 {
+				fmt.Fprintln(stderr, "* cache hit")
+			}
+			s.lastDuration = 0
+			response := *result
+			response.ID = request.ID
+			return &response, nil
+		}
+	}
+	result, err := s.send(request)
+	if err != nil {
+		s.abortRemote(method)
+	}
+	// locked wallet is unlocked with its password and the call is retried once
+	if err == nil && result.Error != nil && s.unlockWallet(method, result.Error) {
+		result, err = s.send(s.newRequest(method, params...))
+	}
+	if cached && err == nil {
+		s.cache.put(key, result)
+	}
+	return result, err
+}
+
+// send sends request, recording its duration and outcome
+func (s *session) send(request *jsonrpc.RPCRequest) (*jsonrpc.RPCResponse, error) {
+	ctx, cancel := s.callContext()
+	defer cancel()
+	if s.secretBody {
+		ctx = withSecretBody(ctx)
+	}
+	start := time.Now()
+	result, err := s.client.SendContext(ctx, request)
+	s.lastDuration = time.Since(start)
+	s.logCall(request, s.lastDuration, result, err)
+	return result, err
+}
+
+// deadline bounds total time of all calls including retries, zero unless --deadline is set
+var deadline time.Time
+
+// deadlineExceeded reports whether --deadline has passed
+func deadlineExceeded() bool {
+	return !deadline.IsZero() && !time.Now().Before(deadline)
+}
+
+// callContext returns context of a single call, cancelled on shutdown, after --call-timeout or at --deadline
+func (s *session) callContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(shutdown)
+	cancels := []context.CancelFunc{cancel}
+	if !deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		cancels = append(cancels, cancel)
+	}
+	if s.callTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.callTimeout)
+		cancels = append(cancels, cancel)
+	}
+	return withRecord(ctx, s.recorder), func() {
+		for i := len(cancels) - 1; i >= 0; i-- {
+			cancels[i]()
+		}
+	}
+}
+
+// logCall records call outcome and duration
+func (s *session) logCall(request *jsonrpc.RPCRequest, duration time.Duration, result *jsonrpc.RPCResponse, err error) {
+	fields := map[string]interface{}{
+		"coin":        s.coin,
+		"wallet":      s.wallet,
+		"method":      request.Method,
+		"id":          request.ID,
+		"duration_ms": float64(duration) / float64(time.Millisecond),
+	}
+	if code := s.recorder.lastCode(); code != 0 {
+		fields["status"] = code
+	}
+	switch {
+	case err != nil:
+		fields["outcome"] = "error"
+		fields["error"] = s.callError(err)
+		callLog.log(levelError, "call", fields)
+	case result.Error != nil:
+		fields["outcome"] = "rpc_error"
+		fields["error"] = result.Error
+		callLog.log(levelWarn, "call", fields)
+	default:
+		fields["outcome"] = "ok"
+		callLog.log(levelInfo, "call", fields)
+	}
+}
+
+// httpError returns user-facing message for HTTP response which isn't a JSON-RPC one
+func httpError(code int, status string, body []byte) string {
+	message := "daemon returned HTTP " + status
+	body = bytes.TrimSpace(body)
+	if len(body) > maxErrorBody {
+		body = append(body[:maxErrorBody:maxErrorBody], "..."...)
+	}
+	if len(body) > 0 {
+		message += ": " + string(body)
+	}
+	return message
+}
+
+// authError returns message for daemon rejecting credentials, naming the flags to check.
+// Verbose mode adds used authentication scheme
+func (s *session) authError(status string) string {
+	authorization := s.client.Headers["Authorization"]
+	scheme := strings.ToLower(strings.SplitN(authorization, " ", 2)[0])
+	var message string
+	if scheme == "bearer" {
+		message = fmt.Sprintf("authentication failed with bearer token at %s; check --token or BITCART_TOKEN", s.client.URL)
+	} else {
+		message = fmt.Sprintf("authentication failed for user %q at %s; check --user/--password or BITCART_PASSWORD", s.user, s.client.URL)
+	}
+	if s.verbose > 0 {
+		message += fmt.Sprintf(" (HTTP %s, %s auth)", status, scheme)
+	}
+	return message
+}
+
+// isAuthFailure reports whether HTTP status code means credentials were rejected
+func isAuthFailure(code int) bool {
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
+
+// decodeError returns message for successful HTTP response which isn't JSON-RPC, e.g. from proxy
+func (s *session) decodeError() string {
+	body := s.recorder.last()
+	if len(bytes.TrimSpace(body)) == 0 {
+		return fmt.Sprintf("daemon returned HTTP %s with empty body, expected JSON-RPC response", s.recorder.lastStatus())
+	}
+	return httpError(s.recorder.lastCode(), s.recorder.lastStatus(), body) + " (not a JSON-RPC response)"
+}
+
+// unreachableError returns message for daemon which couldn't be connected to, most likely not started.
+// Verbose mode adds the original error
+func (s *session) unreachableError(err error) string {
+	message := fmt.Sprintf("could not reach %s daemon at %s - is it running? (see %s on starting daemons)", s.coin, s.client.URL, docsURL)
+	if strings.Contains(err.Error(), "no such host") {
+		message = fmt.Sprintf("could not reach %s daemon at %s - host not found, check the URL", s.coin, s.client.URL)
+	}
+	if s.verbose > 0 {
+		message += ": " + err.Error()
+	}
+	return message
+}
+
+// callError returns user-facing message for transport error
+func (s *session) callError(err error) string {
+	if interrupted() {
+		return "interrupted"
+	}
+	switch err := err.(type) {
+	case *jsonrpc.HTTPError:
+		if isAuthFailure(err.Code) {
+			return s.authError(s.recorder.lastStatus())
+		}
+		return httpError(err.Code, s.recorder.lastStatus(), s.recorder.last())
+	case *bitcart.StatusError:
+		if isAuthFailure(err.Code) {
+			return s.authError(err.Status)
+		}
+		return httpError(err.Code, err.Status, err.Body)
+	}
+	if strings.Contains(err.Error(), "could not decode body to rpc response") {
+		return s.decodeError()
+	}
+	if bitcart.IsTimeout(err) {
+		return fmt.Sprintf("request timed out after %v", s.timeout)
+	}
+	if strings.Contains(err.Error(), context.DeadlineExceeded.Error()) && deadlineExceeded() {
+		return "deadline exceeded (--deadline), giving up"
+	}
+	if strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		return fmt.Sprintf("call timed out after %v (--call-timeout)", s.callTimeout)
+	}
+	if bitcart.IsConnectionFailure(err) && !deadlineExceeded() {
+		return s.unreachableError(err)
+	}
+	if strings.Contains(err.Error(), "x509:") {
+		return err.Error() + " (use --ca-cert to trust daemon CA, or --insecure to skip certificate verification, development only)"
+	}
+	if deadlineExceeded() {
+		// retries were stopped by deadline
+		return err.Error() + " (--deadline exceeded, giving up)"
+	}
+	return err.Error()
+}
+
+// request returns JSON-RPC request which would be sent, with secret headers redacted
+func (s *session) request(method string, params ...interface{}) map[string]interface{} {
+	body := map[string]interface{}{
+		"jsonrpc": s.client.RPCVersion,
+		"method":  method,
+		"id":      s.nextID,
+	}
+	if s.notify {
+		delete(body, "id")
+		if s.client.RPCVersion == "1.0" {
+			body["id"] = nil
+		}
+	}
+	if !s.noWallet {
+		body["xpub"] = s.wallet
+	}
+	if p := jsonrpc.Params(params...); p != nil {
+		body["params"] = p
+	}
+	return map[string]interface{}{
+		"url":     s.client.URL,
+		"headers": bitcart.RedactHeaders(s.client.Headers),
+		"body":    body,
+	}
+}
+
+// notification sends method call as JSON-RPC notification. Daemon sends no result, so nothing is printed
+func (s *session) notification(method string, params interface{}) error {
+	start := time.Now()
+	ctx, cancel := s.callContext()
+	defer cancel()
+	if err := s.client.Notify(ctx, method, params); err != nil {
+		return s.failCall(err)
+	}
+	if s.verbose > 0 {
+		fmt.Fprintf(stderr, "* notification sent in %v\n", time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+// call calls RPC method and prints either error if found or result
+func (s *session) call(method string, params interface{}) error {
+	if s.dryRun {
+		return s.output.write(s.request(method, params))
+	}
+	if s.verbose > 0 {
+		b, _ := json.Marshal(params)
+		fmt.Fprintf(stderr, "* method: %s\n* params: %s\n", method, b)
+	}
+	if s.notify {
+		return s.notification(method, params)
+	}
+	if s.output.ndjson {
+		return s.stream(method, params)
+	}
+	var result *jsonrpc.RPCResponse
+	var err error
+	if s.pageSize > 0 {
+		if result, err = s.paginate(method, params, s.pageSize); err != nil {
+			return fail(err, exitFailure)
+		}
+	} else if result, err = s.do(method, params); err != nil {
+		return s.failCall(err)
+	}
+	var errorValue, resultValue interface{} = rpcError{result.Error, s.lastID}, result.Result
+	if s.output.raw {
+		var raw rawResponse
+		if err := json.Unmarshal(s.recorder.last(), &raw); err == nil {
+			errorValue, resultValue = raw.Error, raw.Result
+		}
+	}
+	if result.Error != nil {
+		return s.printRPCError(result.Error, errorValue)
+	}
+	if err := s.validateResult(result.Result); err != nil {
+		return err
+	}
+	empty := s.failOnEmpty && isEmpty(result.Result)
+	if s.output.selectPath != "" {
+		if resultValue, err = selectPath(resultValue, s.output.selectPath); err != nil {
+			return fail(err, exitFailure)
+		}
+	}
+	if len(s.output.filters) > 0 {
+		if resultValue, err = filterValue(resultValue, s.output.filters); err != nil {
+			return fail(err, exitFailure)
+		}
+	}
+	if s.output.count {
+		count, err := countValue(resultValue)
+		if err != nil {
+			return fail(err, exitFailure)
+		}
+		resultValue = count
+	}
+	switch {
+	case onlyError:
+	case s.prom:
+		err = s.writeMetric(method, resultValue)
+	default:
+		if s.output.withMeta {
+			resultValue = map[string]interface{}{"meta": s.meta(method), "result": resultValue}
+		}
+		err = s.output.write(resultValue)
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.runHook(method, resultValue); err != nil {
+		return fail(err, hookExitCode(err))
+	}
+	if err := s.checkAssertions(result.Result); err != nil {
+		return err
+	}
+	if empty {
+		return fail("result is empty", exitEmptyResult)
+	}
+	return nil
+}
+
+// isEmpty reports whether decoded result is null, empty array or empty object
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// meta returns details of the last call printed by --with-meta
+func (s *session) meta(method string) map[string]interface{} {
+	meta := map[string]interface{}{
+		"coin":        s.coin,
+		"method":      method,
+		"id":          s.lastID,
+		"url":         s.client.URL,
+		"duration_ms": float64(s.lastDuration) / float64(time.Millisecond),
+	}
+	if code := s.recorder.lastCode(); code != 0 {
+		meta["status"] = code
+		meta["status_text"] = s.recorder.lastStatus()
+	}
+	return meta
+}
+
+// printRPCError prints JSON-RPC error value to stderr and returns exit error matching its code.
+// With --legacy-errors the error object is printed to stdout as indented JSON and the call succeeds,
+// with --only-error it is printed to stdout and with --only-result only exit code is left
+func (s *session) printRPCError(rpcErr *jsonrpc.RPCError, value interface{}) error {
+	if onlyResult {
+		return cli.NewExitError("", rpcExitCode(rpcErr.Code))
+	}
+	if legacyErrors {
+		b, err := json.MarshalIndent(rpcErr, "", "  ")
+		if err != nil {
+			return fail(err, exitFailure)
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	if errorJSON {
+		reportError(errorInfo{Type: "rpc", Message: rpcErr.Message, Code: rpcErr.Code, Data: rpcErr.Data, ID: &s.lastID})
+		return cli.NewExitError("", rpcExitCode(rpcErr.Code))
+	}
+	b, err := s.output.marshal(value)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	if onlyError {
+		fmt.Println(string(b))
+		return cli.NewExitError("", rpcExitCode(rpcErr.Code))
+	}
+	fmt.Fprintln(stderr, string(b))
+	if rpcErr.Data != nil {
+		if data, err := s.output.marshal(rpcErr.Data); err == nil {
+			fmt.Fprintln(stderr, "Error data:", string(data))
+		}
+	}
+	return cli.NewExitError("", rpcExitCode(rpcErr.Code))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// chainSeparator separates methods chained in one command line
+const chainSeparator = "--"
+
+// splitChain splits arguments into "method args" calls separated by chainSeparator
+func splitChain(args []string) ([][]string, error) {
+	calls := [][]string{{}}
+	for _, arg := range args {
+		if arg == chainSeparator {
+			calls = append(calls, []string{})
+			continue
+		}
+		calls[len(calls)-1] = append(calls[len(calls)-1], arg)
+	}
+	for _, call := range calls {
+		if len(call) == 0 {
+			return nil, errors.New("no method between -- separators")
+		}
+	}
+	return calls, nil
+}
+
+// runChain calls chained methods in order against the session client, each result follows a header line.
+// It stops after the first failed call unless keepGoing is set
+func (s *session) runChain(calls [][]string, raw bool, encoding string) error {
+	var failure error
+	for i, call := range calls {
+		err := s.chainCall(i, call[0], call[1:], raw, encoding)
+		if err == nil {
+			continue
+		}
+		if failure == nil {
+			failure = err
+		}
+		if !s.keepGoing || interrupted() {
+			break
+		}
+	}
+	return failure
+}
+
+// chainCall parses arguments and calls method at position i of the chain, after its header line
+func (s *session) chainCall(i int, method string, args []string, raw bool, encoding string) error {
+	params, err := parseArgs(args, raw, encoding, os.Stdin)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	method, prepared, err := s.prepareCall(method, params, askStdin)
+	if err != nil {
+		return err
+	}
+	if i > 0 {
+		fmt.Println()
+	}
+	fmt.Printf("==> %s <==\n", method)
+	return s.call(method, prepared)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+)
+
+// responseRecord keeps status and body of the last response of session calls
+type responseRecord struct {
+	mu     sync.Mutex
+	status string
+	code   int
+	body   []byte
+}
+
+// recordKey is context key of responseRecord receiving response of request
+type recordKey struct{}
+
+// withRecord returns ctx whose requests are recorded in record, so concurrent calls sharing client
+// don't see each other's responses
+func withRecord(ctx context.Context, record *responseRecord) context.Context {
+	return context.WithValue(ctx, recordKey{}, record)
+}
+
+// set replaces recorded response
+func (r *responseRecord) set(status string, code int, body []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status, r.code, r.body = status, code, body
+}
+
+// last returns body of the last response
+func (r *responseRecord) last() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.body
+}
+
+// lastCode returns status code of the last response
+func (r *responseRecord) lastCode() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.code
+}
+
+// lastStatus returns status line of the last response, like "401 Unauthorized"
+func (r *responseRecord) lastStatus() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// bodyRecorder is http transport which keeps status and body of the last response in record
+// of request context, or in its own record for requests without one.
+// With statusOnly body is not kept, so streamed responses aren't held in memory
+type bodyRecorder struct {
+	transport  http.RoundTripper
+	statusOnly bool
+	record     *responseRecord
+}
+
+func (r *bodyRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	record, ok := req.Context().Value(recordKey{}).(*responseRecord)
+	if !ok {
+		record = r.record
+	}
+	// failed requests leave no status from earlier response
+	record.set("", 0, nil)
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.statusOnly {
+		record.set(resp.Status, resp.StatusCode, nil)
+		return resp, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	record.set(resp.Status, resp.StatusCode, body)
+	return resp, nil
+}
+
+// verboseTransport is http transport which logs requests and responses to stderr.
+// Secret headers are redacted unless level is 2 or higher
+type verboseTransport struct {
+	transport http.RoundTripper
+	level     int
+}
+
+func (t *verboseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fmt.Fprintf(stderr, "> %s %s\n", req.Method, req.URL)
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := req.Header.Get(name)
+		if bitcart.IsSecretHeader(name) && t.level < 2 {
+			value = "<redacted>"
+		}
+		fmt.Fprintf(stderr, "> %s: %s\n", name, value)
+	}
+	if hasSecretBody(req) {
+		fmt.Fprintln(stderr, "> <redacted>")
+	} else if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := ioutil.ReadAll(body)
+			fmt.Fprintf(stderr, "> %s\n", data)
+		}
+	}
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		fmt.Fprintf(stderr, "< error: %v\n", err)
+		return nil, err
+	}
+	// responses to secret requests, like seeds of created wallets, hold secrets too
+	if hasSecretBody(req) {
+		fmt.Fprintf(stderr, "< %s %s\n< <redacted>\n", resp.Proto, resp.Status)
+		return resp, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	// protocol shows whether HTTP/2 was negotiated with https daemon
+	fmt.Fprintf(stderr, "< %s %s\n< %s\n", resp.Proto, resp.Status, bytes.TrimSpace(body))
+	return resp, nil
+}
+
+// noWalletTransport is http transport which removes xpub field from JSON-RPC request body, or from each
+// request of batch body
+type noWalletTransport struct {
+	transport http.RoundTripper
+}
+
+func (t *noWalletTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil {
+		return t.transport.RoundTrip(req)
+	}
+	data, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	var batch []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err == nil {
+		delete(fields, "xpub")
+		if data, err = json.Marshal(fields); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(data, &batch); err == nil {
+		for _, fields := range batch {
+			delete(fields, "xpub")
+		}
+		if data, err = json.Marshal(batch); err != nil {
+			return nil, err
+		}
+	}
+	req = req.Clone(req.Context())
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.ContentLength = int64(len(data))
+	return t.transport.RoundTrip(req)
+}
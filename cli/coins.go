@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+)
+
+// allCoins is special coin name selecting every configured coin
+const allCoins = "all"
+
+// coinAliases maps alternative coin names to coin names, extended by coin_aliases from config file
+var coinAliases = map[string]string{
+	"bitcoin":  "btc",
+	"litecoin": "ltc",
+	"gravity":  "gzro",
+}
+
+// fallbackURLs are backup daemon URLs of coins from config file, tried in order when daemon is unreachable
+var fallbackURLs = map[string][]string{}
+
+// resolveCoin returns coin name for alias, coin names and unknown names are returned unchanged
+func resolveCoin(name string, coins map[string]string) string {
+	if _, ok := coins[name]; ok {
+		return name
+	}
+	if coin, ok := coinAliases[strings.ToLower(name)]; ok {
+		return coin
+	}
+	return name
+}
+
+// aliasesOf returns sorted aliases of each coin
+func aliasesOf(coins map[string]string) map[string][]string {
+	result := map[string][]string{}
+	for alias, coin := range coinAliases {
+		if _, ok := coins[coin]; ok {
+			result[coin] = append(result[coin], alias)
+		}
+	}
+	for _, aliases := range result {
+		sort.Strings(aliases)
+	}
+	return result
+}
+
+// listCoins prints coins with their resolved URLs and aliases
+func listCoins(coins map[string]string, asJSON bool) error {
+	aliases := aliasesOf(coins)
+	if asJSON {
+		type coinInfo struct {
+			URL     string   `json:"url"`
+			Aliases []string `json:"aliases"`
+		}
+		result := make(map[string]coinInfo, len(coins))
+		for name, url := range coins {
+			result[name] = coinInfo{URL: url, Aliases: append([]string{}, aliases[name]...)}
+		}
+		b, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fail(err, exitFailure)
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	for _, name := range bitcart.SortedCoins(coins) {
+		fmt.Printf("%s\t%s\t%s\n", name, coins[name], strings.Join(aliases[name], ","))
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/chzyer/readline"
+)
+
+// ANSI colors used for JSON highlighting
+const (
+	colorReset   = "\033[0m"
+	colorKey     = "\033[34m"
+	colorString  = "\033[32m"
+	colorNumber  = "\033[36m"
+	colorLiteral = "\033[33m"
+)
+
+// isTerminal reports whether file is a terminal. Other character devices, like /dev/null, are not
+func isTerminal(f *os.File) bool {
+	return readline.IsTerminal(int(f.Fd()))
+}
+
+// useColor decides whether to colorize output: explicit flags win,
+// otherwise color is used on terminals unless NO_COLOR is set
+func useColor(force, disable bool) bool {
+	if disable {
+		return false
+	}
+	if force {
+		return true
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return isTerminal(os.Stdout)
+}
+
+// colorizeJSON highlights keys, strings, numbers and literals of valid JSON
+func colorizeJSON(data []byte) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(data) && data[end] != '"' {
+				if data[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end++
+			if end > len(data) {
+				end = len(data)
+			}
+			// string followed by colon is an object key
+			next := end
+			for next < len(data) && (data[next] == ' ' || data[next] == '\n' || data[next] == '\t' || data[next] == '\r') {
+				next++
+			}
+			color := colorString
+			if next < len(data) && data[next] == ':' {
+				color = colorKey
+			}
+			buf.WriteString(color)
+			buf.Write(data[i:end])
+			buf.WriteString(colorReset)
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i
+			for end < len(data) && bytes.IndexByte([]byte("+-.eE0123456789"), data[end]) >= 0 {
+				end++
+			}
+			buf.WriteString(colorNumber)
+			buf.Write(data[i:end])
+			buf.WriteString(colorReset)
+			i = end
+		case c == 't' || c == 'f' || c == 'n':
+			end := i
+			for end < len(data) && data[end] >= 'a' && data[end] <= 'z' {
+				end++
+			}
+			buf.WriteString(colorLiteral)
+			buf.Write(data[i:end])
+			buf.WriteString(colorReset)
+			i = end
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+	"github.com/urfave/cli"
+)
+
+// completion scripts are based on urfave/cli autocomplete examples
+const bashCompletion = `_bitcart_cli_bash_autocomplete() {
+  local cur opts
+  COMPREPLY=()
+  cur="${COMP_WORDS[COMP_CWORD]}"
+  if [[ "$cur" == "-"* ]]; then
+    opts=$( ${COMP_WORDS[@]:0:$COMP_CWORD} ${cur} --generate-bash-completion )
+  else
+    opts=$( ${COMP_WORDS[@]:0:$COMP_CWORD} --generate-bash-completion )
+  fi
+  COMPREPLY=( $(compgen -W "${opts}" -- ${cur}) )
+  return 0
+}
+
+complete -o bashdefault -o default -o nospace -F _bitcart_cli_bash_autocomplete bitcart-cli
+`
+
+const zshCompletion = `#compdef bitcart-cli
+
+_bitcart_cli_zsh_autocomplete() {
+  local -a opts
+  local cur
+  cur=${words[-1]}
+  if [[ "$cur" == "-"* ]]; then
+    opts=("${(@f)$(_CLI_ZSH_AUTOCOMPLETE_HACK=1 ${words[@]:0:#words[@]-1} ${cur} --generate-bash-completion)}")
+  else
+    opts=("${(@f)$(_CLI_ZSH_AUTOCOMPLETE_HACK=1 ${words[@]:0:#words[@]-1} --generate-bash-completion)}")
+  fi
+
+  if [[ "${opts[1]}" != "" ]]; then
+    _describe 'values' opts
+  else
+    _files
+  fi
+}
+
+compdef _bitcart_cli_zsh_autocomplete bitcart-cli
+`
+
+// printCompletion prints completion script for shell
+func printCompletion(shell string) error {
+	switch strings.ToLower(shell) {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	default:
+		return fail(fmt.Sprintf("unsupported shell %q, use bash or zsh", shell), exitFailure)
+	}
+	return nil
+}
+
+// completeApp completes coin names after --coin flag, and flags or commands otherwise
+func completeApp(c *cli.Context, coins map[string]string) {
+	if len(os.Args) > 2 {
+		switch os.Args[len(os.Args)-2] {
+		case "--coin", "-c":
+			for _, name := range bitcart.SortedCoins(coins) {
+				fmt.Fprintln(c.App.Writer, name)
+				for _, alias := range aliasesOf(coins)[name] {
+					fmt.Fprintln(c.App.Writer, alias)
+				}
+			}
+			return
+		}
+	}
+	cli.DefaultAppComplete(c)
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+)
+
+// coinConfig holds per-coin settings from config file
+type coinConfig struct {
+	URL string `yaml:"url,omitempty"`
+	// URLs are daemon URLs in order of preference, the first one is used unless url is set,
+	// the rest are fallbacks used when daemon can't be connected to
+	URLs     []string `yaml:"urls,omitempty"`
+	User     string   `yaml:"user,omitempty"`
+	Password string   `yaml:"password,omitempty"`
+	Wallet   string   `yaml:"wallet,omitempty"`
+	// RPCVersion overrides --rpc-version for the coin
+	RPCVersion string `yaml:"rpc_version,omitempty"`
+}
+
+// config is the structure of bitcart-cli config file
+type config struct {
+	Coins map[string]coinConfig `yaml:"coins,omitempty"`
+	// DefaultMethod is called when no method is given
+	DefaultMethod string `yaml:"default_method,omitempty"`
+	// Aliases map short names to method names
+	Aliases map[string]string `yaml:"aliases,omitempty"`
+	// CoinAliases map alternative coin names to coin names
+	CoinAliases map[string]string `yaml:"coin_aliases,omitempty"`
+	// DangerousMethods are method name patterns confirmed before calling, replacing defaults
+	DangerousMethods []string `yaml:"dangerous_methods,omitempty"`
+	// CacheMethods are method name patterns cached with --cache, replacing defaults
+	CacheMethods []string `yaml:"cache_methods,omitempty"`
+	// Timeout is request timeout in seconds used unless --timeout is set
+	Timeout int `yaml:"timeout,omitempty"`
+	// Proxy is SOCKS5 proxy URL used unless --proxy is set
+	Proxy string `yaml:"proxy,omitempty"`
+	// Profiles are named settings selected with --profile
+	Profiles map[string]profileConfig `yaml:"profiles,omitempty"`
+	// Credentials are named credential sets referenced by batch entries and --wallets, for multi-tenant daemons
+	Credentials map[string]credentialConfig `yaml:"credentials,omitempty"`
+	// UnlockPasswords map wallet names to passwords unlocking them, used unless --unlock-password is set
+	UnlockPasswords map[string]string `yaml:"unlock_passwords,omitempty"`
+	// CancelMethods map long-running methods to methods stopping them, called on interrupt with --abort-on-interrupt
+	CancelMethods map[string]string `yaml:"cancel_methods,omitempty"`
+	// Shims map friendly method invocations to daemon methods and params, isolating scripts from API changes
+	Shims map[string]shimConfig `yaml:"shims,omitempty"`
+}
+
+// credentialConfig is named credential set, token is used instead of user and password if set
+type credentialConfig struct {
+	User     string `yaml:"user,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	Token    string `yaml:"token,omitempty"`
+}
+
+// profileConfig holds settings of named profile, applied over the rest of config file
+type profileConfig struct {
+	// Coins replace settings of the same coins outside of profile
+	Coins   map[string]coinConfig `yaml:"coins,omitempty"`
+	Timeout int                   `yaml:"timeout,omitempty"`
+}
+
+// useProfile applies settings of named profile
+func (cfg *config) useProfile(name string) error {
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		if len(cfg.Profiles) == 0 {
+			return fmt.Errorf("unknown profile %q; config file defines no profiles", name)
+		}
+		names := make([]string, 0, len(cfg.Profiles))
+		for profileName := range cfg.Profiles {
+			names = append(names, profileName)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown profile %q; available profiles: %s", name, strings.Join(names, ", "))
+	}
+	if cfg.Coins == nil {
+		cfg.Coins = map[string]coinConfig{}
+	}
+	for coin, coinCfg := range profile.Coins {
+		cfg.Coins[coin] = coinCfg
+	}
+	if profile.Timeout != 0 {
+		cfg.Timeout = profile.Timeout
+	}
+	return nil
+}
+
+// expandAlias returns method name for alias, unknown names are returned unchanged
+func (cfg *config) expandAlias(name string) string {
+	if method, ok := cfg.Aliases[name]; ok {
+		return method
+	}
+	return name
+}
+
+// urls returns primary daemon URL and fallbacks of coin, primary is empty if not configured
+func (coinCfg coinConfig) urls() (string, []string) {
+	if coinCfg.URL != "" {
+		return coinCfg.URL, coinCfg.URLs
+	}
+	if len(coinCfg.URLs) == 0 {
+		return "", nil
+	}
+	return coinCfg.URLs[0], coinCfg.URLs[1:]
+}
+
+// dangerousMethods returns method patterns which need confirmation
+func dangerousMethods(cfg *config) []string {
+	if cfg.DangerousMethods != nil {
+		return cfg.DangerousMethods
+	}
+	return defaultDangerousMethods
+}
+
+// cacheMethods returns method patterns which are cached with --cache
+func cacheMethods(cfg *config) []string {
+	if cfg.CacheMethods != nil {
+		return cfg.CacheMethods
+	}
+	return defaultCacheMethods
+}
+
+// defaultConfigPath returns path to config file in user's home directory
+func defaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".bitcart", "config.yaml")
+}
+
+// loadConfig reads config file from path. Missing file is not an error unless required is set
+func loadConfig(path string, required bool) (*config, error) {
+	cfg := &config{}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return cfg, nil
+		}
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %v", path, err)
+	}
+	if err := cfg.interpolate(); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// loadCoinFile reads map of coin names to daemon URLs from JSON or YAML file
+func loadCoinFile(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is valid YAML, so both are parsed the same way
+	coins := map[string]string{}
+	if err := yaml.UnmarshalStrict(data, &coins); err != nil {
+		return nil, fmt.Errorf("parsing coin file %s: %v", path, err)
+	}
+	for name, url := range coins {
+		if url == "" {
+			return nil, fmt.Errorf("parsing coin file %s: empty URL of %s", path, name)
+		}
+	}
+	return coins, nil
+}
+
+// envReference matches ${VAR} and ${VAR:-default} in config values
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandEnv replaces environment variable references in value.
+// Unset variables are an error, unless default is given
+func expandEnv(value string) (string, error) {
+	var err error
+	result := envReference.ReplaceAllStringFunc(value, func(ref string) string {
+		match := envReference.FindStringSubmatch(ref)
+		if env, ok := os.LookupEnv(match[1]); ok {
+			return env
+		}
+		if match[2] == "" && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", match[1])
+		}
+		return match[3]
+	})
+	return result, err
+}
+
+// interpolate expands environment variable references in all string values
+func (cfg *config) interpolate() error {
+	var err error
+	expand := func(value *string) {
+		if err == nil {
+			*value, err = expandEnv(*value)
+		}
+	}
+	expandCoins := func(coins map[string]coinConfig) {
+		for name, coinCfg := range coins {
+			for _, value := range []*string{&coinCfg.URL, &coinCfg.User, &coinCfg.Password, &coinCfg.Wallet, &coinCfg.RPCVersion} {
+				expand(value)
+			}
+			for i := range coinCfg.URLs {
+				expand(&coinCfg.URLs[i])
+			}
+			coins[name] = coinCfg
+		}
+	}
+	expand(&cfg.DefaultMethod)
+	expand(&cfg.Proxy)
+	expandCoins(cfg.Coins)
+	for _, profile := range cfg.Profiles {
+		expandCoins(profile.Coins)
+	}
+	for name, creds := range cfg.Credentials {
+		expand(&creds.User)
+		expand(&creds.Password)
+		expand(&creds.Token)
+		cfg.Credentials[name] = creds
+	}
+	for _, aliases := range []map[string]string{cfg.Aliases, cfg.CoinAliases, cfg.UnlockPasswords, cfg.CancelMethods} {
+		for name, value := range aliases {
+			expand(&value)
+			aliases[name] = value
+		}
+	}
+	for i := range cfg.DangerousMethods {
+		expand(&cfg.DangerousMethods[i])
+	}
+	for i := range cfg.CacheMethods {
+		expand(&cfg.CacheMethods[i])
+	}
+	return err
+}
+
+// readSecretFile returns first line of file holding password or token
+func readSecretFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	line := strings.SplitN(string(data), "\n", 2)[0]
+	return strings.TrimRight(line, "\r"), nil
+}
+
+// redactSecret hides non-empty secret value
+func redactSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "<redacted>"
+}
+
+// effectiveConfig returns settings which would be used for calls, after all
+// defaults, config file, environment variables and flags are merged. Secrets are redacted
+func effectiveConfig(c *cli.Context, coins map[string]string, cfg *config) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{}, len(coins))
+	for name, url := range coins {
+		coin := map[string]interface{}{
+			"url":         url,
+			"fallbacks":   fallbackURLs[name],
+			"rpc_version": resolveRPCVersion(c, name, cfg),
+		}
+		creds, err := resolveCredentials(c, name, cfg)
+		if err != nil {
+			coin["error"] = err.Error()
+		} else {
+			coin["wallet"] = creds.wallet
+			coin["user"] = creds.user
+			coin["password"] = redactSecret(creds.password)
+			coin["token"] = redactSecret(creds.token)
+		}
+		resolved[name] = coin
+	}
+	headers, err := bitcart.ParseHeaders(c.StringSlice("header"))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"config_file":       c.String("config"),
+		"profile":           c.String("profile"),
+		"coin_file":         c.String("coin-file"),
+		"coin":              c.String("coin"),
+		"coins":             resolved,
+		"coin_aliases":      coinAliases,
+		"default_method":    cfg.DefaultMethod,
+		"dangerous_methods": dangerousMethods(cfg),
+		"cache_methods":     cacheMethods(cfg),
+		"aliases":           cfg.Aliases,
+		"timeout":           c.Int("timeout"),
+		"retry":             c.Int("retry"),
+		"proxy":             c.String("proxy"),
+		"insecure":          c.Bool("insecure"),
+		"headers":           bitcart.RedactHeaders(headers),
+	}, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+)
+
+// configHeader starts config files written by config init
+const configHeader = "# written by bitcart-cli config init, see bitcart-cli config show for effective settings\n"
+
+// exportConfig returns config file holding settings resolved from flags, environment and current config file.
+// Passwords, including wallet unlock and profile passwords, are only included with secrets, bearer tokens are only written in credential sets with secrets
+func exportConfig(c *cli.Context, coins map[string]string, cfg *config, secrets bool) (*config, error) {
+	exported := &config{
+		Coins:            make(map[string]coinConfig, len(coins)),
+		DefaultMethod:    cfg.DefaultMethod,
+		Aliases:          cfg.Aliases,
+		CoinAliases:      cfg.CoinAliases,
+		DangerousMethods: cfg.DangerousMethods,
+		CacheMethods:     cfg.CacheMethods,
+		Timeout:          c.Int("timeout"),
+		Proxy:            c.String("proxy"),
+		CancelMethods:    cfg.CancelMethods,
+		Shims:            cfg.Shims,
+	}
+	for name, creds := range cfg.Credentials {
+		if !secrets {
+			creds.Password, creds.Token = "", ""
+		}
+		if exported.Credentials == nil {
+			exported.Credentials = map[string]credentialConfig{}
+		}
+		exported.Credentials[name] = creds
+	}
+	for name, profile := range cfg.Profiles {
+		if !secrets {
+			coins := make(map[string]coinConfig, len(profile.Coins))
+			for coin, coinCfg := range profile.Coins {
+				coinCfg.Password = ""
+				coins[coin] = coinCfg
+			}
+			profile.Coins = coins
+		}
+		if exported.Profiles == nil {
+			exported.Profiles = map[string]profileConfig{}
+		}
+		exported.Profiles[name] = profile
+	}
+	if secrets {
+		exported.UnlockPasswords = cfg.UnlockPasswords
+	}
+	for name, url := range coins {
+		creds, err := resolveCredentials(c, name, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		coinCfg := coinConfig{URL: url, URLs: fallbackURLs[name], User: creds.user, Wallet: creds.wallet}
+		if version := resolveRPCVersion(c, name, cfg); version != bitcart.DefaultRPCVersion {
+			coinCfg.RPCVersion = version
+		}
+		if secrets {
+			coinCfg.Password = creds.password
+		}
+		exported.Coins[name] = coinCfg
+	}
+	return exported, nil
+}
+
+// includeSecrets decides whether passwords are written to config file at path,
+// asking on terminal unless --secrets or --no-secrets is given. Without terminal they are left out
+func includeSecrets(c *cli.Context, path string) (bool, error) {
+	if c.Bool("secrets") || c.Bool("no-secrets") {
+		return c.Bool("secrets"), nil
+	}
+	if !isTerminal(os.Stdin) {
+		fmt.Fprintln(stderr, "Passwords are not written without terminal, pass --secrets to include them")
+		return false, nil
+	}
+	answer, err := askStdin(fmt.Sprintf("Include passwords in %s? [y/N] ", path))
+	if err != nil && answer == "" {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// initConfig writes resolved settings to config file at path, refusing to overwrite it unless force is set
+func initConfig(c *cli.Context, path string, coins map[string]string, cfg *config, force bool) error {
+	if path == "" {
+		return fail("no config file path, pass --config", exitFailure)
+	}
+	if c.Bool("secrets") && c.Bool("no-secrets") {
+		return fail("--secrets can't be combined with --no-secrets", exitFailure)
+	}
+	if _, err := os.Stat(path); err == nil && !force {
+		return fail(fmt.Sprintf("%s already exists, pass --force to overwrite it", path), exitFailure)
+	}
+	secrets, err := includeSecrets(c, path)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	exported, err := exportConfig(rootContext(c), coins, cfg, secrets)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	data, err := yaml.Marshal(exported)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	// file may hold passwords, so only owner can read it
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fail(err, exitFailure)
+	}
+	if err := ioutil.WriteFile(path, append([]byte(configHeader), data...), 0600); err != nil {
+		return fail(err, exitFailure)
+	}
+	fmt.Fprintln(stderr, "Config written to", path)
+	return nil
+}
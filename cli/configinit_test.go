@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExportConfig(t *testing.T) {
+	cfg := &config{
+		Coins:           map[string]coinConfig{"btc": {User: "cfguser", Password: "cfgpass", Wallet: "w", RPCVersion: "1.0"}},
+		Credentials:     map[string]credentialConfig{"ops": {User: "ops", Password: "opspass", Token: "opstoken"}},
+		UnlockPasswords: map[string]string{"w": "unlockpass"},
+		Profiles: map[string]profileConfig{
+			"staging": {Coins: map[string]coinConfig{"btc": {URL: "http://staging:5000", Password: "stagingpass"}}, Timeout: 5},
+		},
+	}
+	coins := map[string]string{"btc": "http://localhost:5000"}
+	c := testContext(t, "--timeout", "10")
+
+	exported, err := exportConfig(c, coins, cfg, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (coinConfig{URL: "http://localhost:5000", User: "cfguser", Wallet: "w", RPCVersion: "1.0"}); !reflect.DeepEqual(exported.Coins["btc"], want) {
+		t.Errorf("exported btc = %+v, want %+v", exported.Coins["btc"], want)
+	}
+	if creds := exported.Credentials["ops"]; creds.User != "ops" || creds.Password != "" || creds.Token != "" {
+		t.Errorf("exported credentials = %+v, want user without secrets", creds)
+	}
+	if exported.UnlockPasswords != nil {
+		t.Errorf("exported unlock passwords = %v without secrets", exported.UnlockPasswords)
+	}
+	profile := exported.Profiles["staging"]
+	if coin := profile.Coins["btc"]; coin.URL != "http://staging:5000" || coin.Password != "" || profile.Timeout != 5 {
+		t.Errorf("exported profile = %+v, want it without password", profile)
+	}
+	if exported.Timeout != 10 {
+		t.Errorf("exported timeout = %d, want 10 from flag", exported.Timeout)
+	}
+	// the config exported from is left unchanged
+	if cfg.Profiles["staging"].Coins["btc"].Password != "stagingpass" || cfg.Credentials["ops"].Password != "opspass" {
+		t.Error("exportConfig() changed passwords of source config")
+	}
+
+	exported, err = exportConfig(c, coins, cfg, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exported.Coins["btc"].Password != "cfgpass" || exported.Credentials["ops"].Token != "opstoken" ||
+		exported.UnlockPasswords["w"] != "unlockpass" || exported.Profiles["staging"].Coins["btc"].Password != "stagingpass" {
+		t.Errorf("exportConfig() with secrets = %+v, want all passwords", exported)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// defaultDangerousMethods are method name patterns confirmed before calling,
+// unless dangerous_methods is set in config file
+var defaultDangerousMethods = []string{"payto", "paytomany", "broadcast", "removelocaltx", "close_channel", "clear_*"}
+
+// matchMethod reports whether method matches any of shell-style patterns
+func matchMethod(method string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, method); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// stdinLines reads confirmation answers, shared so buffered input isn't lost between questions
+var stdinLines = bufio.NewReader(os.Stdin)
+
+// askStdin prints prompt to stderr and reads answer line from stdin
+func askStdin(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	return stdinLines.ReadString('\n')
+}
+
+// confirmation holds settings of confirming dangerous methods
+type confirmation struct {
+	// yes skips confirmation, as does dry run
+	yes bool
+	// strict fails without terminal instead of proceeding
+	strict   bool
+	patterns []string
+}
+
+// newConfirmation loads confirmation settings from flags and config file
+func newConfirmation(c *cli.Context, cfg *config) confirmation {
+	return confirmation{
+		yes:      c.Bool("yes") || c.Bool("dry-run"),
+		strict:   c.Bool("strict-confirm"),
+		patterns: dangerousMethods(cfg),
+	}
+}
+
+// confirm asks whether dangerous method should be called on session coin and wallet
+func (s *session) confirm(method string, ask func(prompt string) (string, error)) error {
+	target := s.coin
+	if s.wallet != "" {
+		target += "/" + s.wallet
+	}
+	return s.confirmation.check(method, target, ask)
+}
+
+// check asks whether dangerous method should be called against target, using ask to read the answer.
+// Without terminal the call proceeds, unless --strict-confirm requires --yes
+func (cf confirmation) check(method, target string, ask func(prompt string) (string, error)) error {
+	if cf.yes || !matchMethod(method, cf.patterns) {
+		return nil
+	}
+	if !isTerminal(os.Stdin) {
+		if cf.strict {
+			return fail(fmt.Sprintf("%s needs confirmation, pass --yes to run it without terminal", method), exitFailure)
+		}
+		return nil
+	}
+	answer, err := ask(fmt.Sprintf("Run %s against %s? [y/N] ", method, target))
+	if err != nil && answer == "" {
+		return fail(err, exitFailure)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	fmt.Fprintln(stderr, "Cancelled")
+	return cli.NewExitError("", exitFailure)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/chzyer/readline"
+)
+
+// createWalletMethod is daemon method creating wallet file with new seed
+const createWalletMethod = "create"
+
+// walletOptions are settings of wallet created by create-wallet
+type walletOptions struct {
+	password   string
+	passphrase string
+	seedType   string
+	encrypt    bool
+	force      bool
+}
+
+// promptWalletPassword asks for wallet password twice on terminal, empty password means none
+func promptWalletPassword() (string, error) {
+	password, err := readline.Password("Wallet password (empty for none): ")
+	if err != nil || len(password) == 0 {
+		return "", err
+	}
+	repeated, err := readline.Password("Repeat password: ")
+	if err != nil {
+		return "", err
+	}
+	if string(repeated) != string(password) {
+		return "", fmt.Errorf("passwords don't match")
+	}
+	return string(password), nil
+}
+
+// createWallet creates wallet file name in daemon wallets directory and prints its seed.
+// Wallets already loaded by daemon are not overwritten unless force is set
+func (s *session) createWallet(name string, opts walletOptions) error {
+	if !opts.force && !s.dryRun {
+		// daemons without list_wallets are left to refuse existing files themselves
+		if wallets, err := s.listWallets(); err == nil {
+			for _, wallet := range wallets {
+				if wallet == name {
+					return fail(fmt.Sprintf("wallet %q already exists, pass --force to create it anyway", name), exitFailure)
+				}
+			}
+		}
+	}
+	params := map[string]interface{}{"wallet_path": name, "encrypt_file": opts.encrypt && opts.password != ""}
+	if opts.password != "" {
+		params["password"] = opts.password
+	}
+	if opts.passphrase != "" {
+		params["passphrase"] = opts.passphrase
+	}
+	if opts.seedType != "" {
+		params["seed_type"] = opts.seedType
+	}
+	if s.dryRun {
+		masked := make(map[string]interface{}, len(params))
+		for key, value := range params {
+			masked[key] = value
+		}
+		for _, key := range []string{"password", "passphrase"} {
+			if _, ok := masked[key]; ok {
+				masked[key] = redactMask
+			}
+		}
+		return s.output.write(s.request(createWalletMethod, masked))
+	}
+	// password and passphrase are never printed or traced
+	ws := *s
+	ws.secretBody = true
+	result, err := ws.do(createWalletMethod, params)
+	s.lastID, s.nextID = ws.lastID, ws.nextID
+	if err != nil {
+		return s.failCall(err)
+	}
+	if result.Error != nil {
+		return s.printRPCError(result.Error, rpcError{result.Error, s.lastID})
+	}
+	if err := s.output.write(result.Result); err != nil {
+		return err
+	}
+	fmt.Fprintln(stderr, "Write down the seed and keep it safe, it is the only way to restore the wallet")
+	return nil
+}
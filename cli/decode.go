@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// decodeField decodes base64 or hex string found at field path of JSON read from r.
+// Empty field decodes the whole value, which must be a string then
+func decodeField(r io.Reader, field, encoding string) ([]byte, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	value, err := selectPath(json.RawMessage(data), field)
+	if err != nil {
+		return nil, err
+	}
+	text, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("value at %q is not a string", field)
+	}
+	text = strings.TrimSpace(text)
+	switch encoding {
+	case "base64":
+		return base64.StdEncoding.DecodeString(text)
+	case "hex":
+		return hex.DecodeString(text)
+	}
+	return nil, errors.New("choose encoding with --base64 or --hex")
+}
+
+// runDecode prints decoded bytes of result field read from stdin, or writes them to output file
+func runDecode(field, encoding, output string) error {
+	data, err := decodeField(os.Stdin, field, encoding)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	if output != "" {
+		if err := ioutil.WriteFile(output, data, 0644); err != nil {
+			return fail(err, exitFailure)
+		}
+		return nil
+	}
+	os.Stdout.Write(data)
+	return nil
+}
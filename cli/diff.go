@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// difference is a path at which results of two daemons differ.
+// Change is "added" if only B has it, "removed" if only A has it and "changed" otherwise
+type difference struct {
+	Path   string      `json:"path"`
+	Change string      `json:"change"`
+	A      interface{} `json:"a"`
+	B      interface{} `json:"b"`
+}
+
+// diffValues appends differences between a and b at path to diffs. Objects are compared by key
+// and lists by index, keys in ignore are skipped by name or by path
+func diffValues(path string, a, b interface{}, ignore map[string]bool, diffs []difference) []difference {
+	objectA, okA := a.(map[string]interface{})
+	objectB, okB := b.(map[string]interface{})
+	if okA && okB {
+		keys := make([]string, 0, len(objectA)+len(objectB))
+		for key := range objectA {
+			keys = append(keys, key)
+		}
+		for key := range objectB {
+			if _, ok := objectA[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			if ignore[key] || ignore[keyPath] {
+				continue
+			}
+			valueA, inA := objectA[key]
+			valueB, inB := objectB[key]
+			switch {
+			case !inA:
+				diffs = append(diffs, difference{Path: keyPath, Change: "added", B: valueB})
+			case !inB:
+				diffs = append(diffs, difference{Path: keyPath, Change: "removed", A: valueA})
+			default:
+				diffs = diffValues(keyPath, valueA, valueB, ignore, diffs)
+			}
+		}
+		return diffs
+	}
+	listA, okA := a.([]interface{})
+	listB, okB := b.([]interface{})
+	if okA && okB {
+		for i := 0; i < len(listA) || i < len(listB); i++ {
+			indexPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(listA):
+				diffs = append(diffs, difference{Path: indexPath, Change: "added", B: listB[i]})
+			case i >= len(listB):
+				diffs = append(diffs, difference{Path: indexPath, Change: "removed", A: listA[i]})
+			default:
+				diffs = diffValues(indexPath, listA[i], listB[i], ignore, diffs)
+			}
+		}
+		return diffs
+	}
+	if !reflect.DeepEqual(a, b) {
+		if path == "" {
+			path = "."
+		}
+		diffs = append(diffs, difference{Path: path, Change: "changed", A: a, B: b})
+	}
+	return diffs
+}
+
+// compared returns value of call compared by diff, either result or {"error": {code, message}}.
+// Transport failures are returned as error
+func (s *session) compared(method string, params interface{}) (interface{}, error) {
+	result, err := s.do(method, params)
+	if err != nil {
+		return nil, errors.New(s.callError(err))
+	}
+	if result.Error != nil {
+		return map[string]interface{}{"error": map[string]interface{}{"code": result.Error.Code, "message": result.Error.Message}}, nil
+	}
+	return result.Result, nil
+}
+
+// runDiff calls method on two daemons and prints differences between their results,
+// failing if there are any
+func runDiff(a, b *session, method string, params interface{}, ignore []string) error {
+	ignored := make(map[string]bool, len(ignore))
+	for _, field := range ignore {
+		ignored[field] = true
+	}
+	valueA, err := a.compared(method, params)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	valueB, err := b.compared(method, params)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	diffs := diffValues("", valueA, valueB, ignored, nil)
+	if len(diffs) == 0 {
+		fmt.Fprintf(stderr, "%s results of %s and %s match\n", method, a.client.URL, b.client.URL)
+		return nil
+	}
+	if err := a.output.write(diffs); err != nil {
+		return err
+	}
+	return cli.NewExitError("", exitFailure)
+}
+
+// diffSession returns session for daemon given to diff as coin name or alias, or as URL of selected coin daemon.
+// Daemons given by URL have no fallbacks, so the compared daemon is the one called
+func diffSession(c *cli.Context, spec string, coins map[string]string, cfg *config) (*session, error) {
+	if !strings.Contains(spec, "://") {
+		return newSession(c, resolveCoin(spec, coins), coins, cfg)
+	}
+	coin := c.String("coin")
+	if coin == allCoins {
+		return nil, fmt.Errorf("daemon %s needs --coin to select coin it serves", spec)
+	}
+	overridden := make(map[string]string, len(coins))
+	for name, url := range coins {
+		overridden[name] = url
+	}
+	overridden[coin] = spec
+	fallbacks := fallbackURLs[coin]
+	delete(fallbackURLs, coin)
+	defer func() {
+		if fallbacks != nil {
+			fallbackURLs[coin] = fallbacks
+		}
+	}()
+	return newSession(c, coin, overridden, cfg)
+}
+
+// diffCommand runs diff subcommand
+func diffCommand(c *cli.Context, coins map[string]string, cfg *config) error {
+	root := rootContext(c)
+	if c.String("coin-a") == "" || c.String("coin-b") == "" {
+		return fail("diff needs --coin-a and --coin-b", exitFailure)
+	}
+	args := c.Args()
+	if len(args) == 0 {
+		return fail("diff needs method to call", exitFailure)
+	}
+	a, err := diffSession(root, c.String("coin-a"), coins, cfg)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	b, err := diffSession(root, c.String("coin-b"), coins, cfg)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	params, err := parseArgs(args[1:], root.Bool("raw-args"), root.String("encode-params"), os.Stdin)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	method, params, err := a.resolveCall(args[0], params)
+	if err != nil {
+		return err
+	}
+	// method runs on both daemons, so it is confirmed once for both before either call
+	target := fmt.Sprintf("%s and %s", a.client.URL, b.client.URL)
+	if err := newConfirmation(root, cfg).check(method, target, askStdin); err != nil {
+		return err
+	}
+	return runDiff(a, b, method, params, splitList(c.StringSlice("ignore")))
+}
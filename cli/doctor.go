@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MrNaif2018/jsonrpc"
+	"github.com/urfave/cli"
+)
+
+// slowLatency is round trip time above which doctor warns about latency
+const slowLatency = time.Second
+
+// check statuses reported by doctor
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// check is outcome of one diagnostic check of coin daemon
+type check struct {
+	coin   string
+	name   string
+	status string
+	detail string
+}
+
+// diagnose checks that daemon of session coin is reachable, accepts credentials, responds quickly
+// and reports its version, all with a single version call
+func (s *session) diagnose() []check {
+	newCheck := func(name, status, detail string) check {
+		return check{coin: s.coin, name: name, status: status, detail: detail}
+	}
+	start := time.Now()
+	result, err := s.do(pingMethod)
+	elapsed := time.Since(start)
+	skipped := "daemon is unreachable"
+	if err != nil {
+		httpErr, ok := err.(*jsonrpc.HTTPError)
+		if !ok || !isAuthFailure(httpErr.Code) {
+			return []check{
+				newCheck("reachable", checkFail, s.callError(err)),
+				newCheck("auth", checkSkip, skipped),
+				newCheck("latency", checkSkip, skipped),
+				newCheck("version", checkSkip, skipped),
+			}
+		}
+	}
+	checks := []check{newCheck("reachable", checkPass, s.client.URL)}
+	if err != nil {
+		checks = append(checks, newCheck("auth", checkFail, s.callError(err)))
+	} else {
+		checks = append(checks, newCheck("auth", checkPass, "credentials accepted"))
+	}
+	latency := fmt.Sprintf("%dms", elapsed.Milliseconds())
+	if elapsed > slowLatency {
+		checks = append(checks, newCheck("latency", checkWarn, fmt.Sprintf("%s, slower than %v", latency, slowLatency)))
+	} else {
+		checks = append(checks, newCheck("latency", checkPass, latency))
+	}
+	switch {
+	case err != nil:
+		checks = append(checks, newCheck("version", checkSkip, "authentication failed"))
+	case result.Error != nil:
+		checks = append(checks, newCheck("version", checkWarn, fmt.Sprintf("%s failed: %s", pingMethod, result.Error.Message)))
+	default:
+		version, _ := versionString(result.Result)
+		checks = append(checks, newCheck("version", checkPass, version))
+	}
+	return checks
+}
+
+// runDoctor runs diagnostic checks of each coin daemon and prints them as table with summary.
+// It fails if any check failed, warnings don't affect exit code
+func runDoctor(coins []string, newCoinSession func(coin string) (*session, error)) error {
+	var checks []check
+	for _, coin := range coins {
+		s, err := newCoinSession(coin)
+		if err != nil {
+			checks = append(checks, check{coin: coin, name: "config", status: checkFail, detail: err.Error()})
+			continue
+		}
+		checks = append(checks, s.diagnose()...)
+	}
+	counts := map[string]int{}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COIN\tCHECK\tSTATUS\tDETAIL")
+	for _, check := range checks {
+		counts[check.status]++
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", check.coin, check.name, check.status, check.detail)
+	}
+	w.Flush()
+	fmt.Printf("\n%d passed, %d warnings, %d failed, %d skipped\n", counts[checkPass], counts[checkWarn], counts[checkFail], counts[checkSkip])
+	if counts[checkFail] > 0 {
+		return cli.NewExitError("", exitFailure)
+	}
+	return nil
+}
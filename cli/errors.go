@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+	"github.com/MrNaif2018/jsonrpc"
+	"github.com/urfave/cli"
+)
+
+// error output settings, set from --error-json, --error-stdout and --legacy-errors
+var (
+	errorJSON   bool
+	errorStdout bool
+	// legacyErrors prints failed calls to stdout and exits 0, as the CLI did before exit codes
+	legacyErrors bool
+	// onlyResult hides errors leaving only exit code, onlyError prints errors to stdout and hides results
+	onlyResult bool
+	onlyError  bool
+)
+
+// errorInfo describes failure printed in --error-json mode.
+// Type is "rpc" for JSON-RPC errors, "http" for HTTP error statuses,
+// "transport" for other failed calls, "validation" for results not matching --validate schema,
+// "incomplete" for --ndjson streams which broke off and "error" for everything else
+type errorInfo struct {
+	Type    string      `json:"type"`
+	Message string      `json:"message"`
+	Code    int         `json:"code,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+	ID      *int        `json:"id,omitempty"`
+}
+
+// errorWriter returns where errors are printed
+func errorWriter() io.Writer {
+	if errorStdout || onlyError {
+		return os.Stdout
+	}
+	return stderr
+}
+
+// reportError prints error as "Error: message" line, or as {"error": {...}} object in --error-json mode
+func reportError(info errorInfo) {
+	if onlyResult {
+		return
+	}
+	if !errorJSON {
+		fmt.Fprintln(errorWriter(), "Error:", info.Message)
+		return
+	}
+	b, err := json.Marshal(map[string]interface{}{"error": info})
+	if err != nil {
+		b, _ = json.Marshal(map[string]interface{}{"error": errorInfo{Type: info.Type, Message: info.Message, Code: info.Code}})
+	}
+	fmt.Fprintln(errorWriter(), string(b))
+}
+
+// fail reports error and returns exit error with code
+func fail(err interface{}, code int) error {
+	reportError(errorInfo{Type: "error", Message: fmt.Sprint(err)})
+	return cli.NewExitError("", code)
+}
+
+// failCall reports failed call and returns exit error
+func (s *session) failCall(err error) error {
+	if partial, ok := err.(*bitcart.PartialResultError); ok {
+		reportError(errorInfo{
+			Type:    "incomplete",
+			Message: fmt.Sprintf("stream is incomplete, %v", partial),
+			Data:    map[string]int{"elements": partial.Elements},
+			ID:      &s.lastID,
+		})
+		return cli.NewExitError("", exitIncompleteStream)
+	}
+	if legacyErrors {
+		fmt.Println("Error:", s.callError(err))
+		return nil
+	}
+	info := errorInfo{Type: "transport", Message: s.callError(err), ID: &s.lastID}
+	switch err := err.(type) {
+	case *jsonrpc.HTTPError:
+		info.Type, info.Code = "http", err.Code
+	case *bitcart.StatusError:
+		info.Type, info.Code = "http", err.Code
+	}
+	reportError(info)
+	return cli.NewExitError("", exitFailure)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/MrNaif2018/jsonrpc"
+	"github.com/urfave/cli"
+)
+
+// entry converts call outcome into {"result": ...} or {"error": ...} object
+// used in batch and fan-out output. ok is false if call failed
+func (s *session) entry(method string, result *jsonrpc.RPCResponse, err error) (map[string]interface{}, bool) {
+	switch {
+	case err != nil:
+		return map[string]interface{}{"error": map[string]interface{}{"message": s.callError(err), "id": s.lastID}}, false
+	case result.Error != nil:
+		return map[string]interface{}{"error": rpcError{result.Error, s.lastID}}, false
+	}
+	if err := s.runHook(method, result.Result); err != nil {
+		return map[string]interface{}{"error": map[string]interface{}{"message": err.Error(), "id": s.lastID}}, false
+	}
+	return map[string]interface{}{"result": result.Result}, true
+}
+
+// skippedEntry replaces results of calls not made because of --stop-on-error
+var skippedEntry = map[string]interface{}{"error": map[string]string{"message": "skipped after earlier failure"}}
+
+// parallel calls fn for each index in [0, n), running at most concurrency calls at once.
+// fn reports whether call succeeded. With stopOnFailure, indexes not started after a failure
+// are skipped and marked in returned slice, calls in flight are finished
+func parallel(n, concurrency int, stopOnFailure bool, fn func(i int) bool) (skipped []bool) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	skipped = make([]bool, n)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := false
+	slots := make(chan struct{}, concurrency)
+	for i := 0; i < n; i++ {
+		slots <- struct{}{}
+		mu.Lock()
+		stop := failed && stopOnFailure
+		mu.Unlock()
+		if stop {
+			skipped[i] = true
+			<-slots
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if !fn(i) {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+			<-slots
+		}(i)
+	}
+	wg.Wait()
+	return skipped
+}
+
+// runWallets calls method once per wallet and prints results keyed by wallet name, with request ids
+// following the session one in wallet order.
+// Wallet given as wallet@name is called with credential set name from config file.
+// Failed calls don't stop the others and appear as error entries
+func (s *session) runWallets(wallets []string, method string, params interface{}) error {
+	entries := make([]interface{}, len(wallets))
+	oks := make([]bool, len(wallets))
+	names := make([]string, len(wallets))
+	sets := make([]string, len(wallets))
+	for i, wallet := range wallets {
+		names[i] = wallet
+		if at := strings.LastIndex(wallet, "@"); at >= 0 {
+			names[i], sets[i] = wallet[:at], wallet[at+1:]
+		}
+	}
+	skipped := parallel(len(wallets), s.concurrency, s.stopOnError, func(i int) bool {
+		cs, err := s.withCredentials(sets[i], "", "")
+		if err != nil {
+			entries[i] = map[string]interface{}{"error": map[string]string{"message": err.Error()}}
+			return false
+		}
+		ws := *cs
+		ws.wallet = names[i]
+		ws.recorder = &responseRecord{}
+		// each wallet call has its own request id for correlation
+		ws.nextID = s.nextID + i
+		if s.dryRun {
+			entries[i], oks[i] = ws.request(method, params), true
+			return true
+		}
+		result, err := ws.do(method, params)
+		entry, ok := ws.entry(method, result, err)
+		if ok {
+			entry["id"] = ws.lastID
+		}
+		entries[i], oks[i] = entry, ok
+		return ok
+	})
+	s.nextID += len(wallets)
+	markSkipped(entries, skipped)
+	return writeResults(s.output, names, entries, oks)
+}
+
+// runCoins calls method on each coin with its own client and prints results keyed by coin name.
+// Failed coins don't stop the others and appear as error entries.
+// Coins which failed recently are skipped according to h
+func runCoins(coins []string, newCoinSession func(coin string) (*session, error), output outputOptions, concurrency int, stopOnError bool, h *health, method string, params interface{}) error {
+	entries := make([]interface{}, len(coins))
+	oks := make([]bool, len(coins))
+	skipped := parallel(len(coins), concurrency, stopOnError, func(i int) bool {
+		if err := h.skip(coins[i]); err != nil {
+			entries[i] = map[string]interface{}{"error": map[string]string{"message": err.Error()}}
+			return false
+		}
+		s, err := newCoinSession(coins[i])
+		if err != nil {
+			entries[i] = map[string]interface{}{"error": map[string]string{"message": err.Error()}}
+			return false
+		}
+		if s.dryRun {
+			entries[i], oks[i] = s.request(method, params), true
+			return true
+		}
+		result, err := s.do(method, params)
+		entries[i], oks[i] = s.entry(method, result, err)
+		// JSON-RPC errors mean daemon is alive
+		h.report(coins[i], err == nil)
+		return oks[i]
+	})
+	markSkipped(entries, skipped)
+	return writeResults(output, coins, entries, oks)
+}
+
+// markSkipped sets entries of skipped calls to skippedEntry
+func markSkipped(entries []interface{}, skipped []bool) {
+	for i := range entries {
+		if skipped[i] {
+			entries[i] = skippedEntry
+		}
+	}
+}
+
+// writeResults prints entries keyed by names, failing if any of them isn't ok
+func writeResults(output outputOptions, names []string, entries []interface{}, oks []bool) error {
+	results := make(map[string]interface{}, len(names))
+	failed := false
+	for i, name := range names {
+		results[name] = entries[i]
+		failed = failed || !oks[i]
+	}
+	if err := output.write(results); err != nil {
+		return err
+	}
+	if failed {
+		return cli.NewExitError("", exitRPCError)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// filterExpr matches --filter expressions like height>1000 or label=coffee
+var filterExpr = regexp.MustCompile(`^([^=!<>]+)(=|!=|>=|<=|>|<)(.*)$`)
+
+// filter keeps list elements whose field at path compares to value with op
+type filter struct {
+	path  string
+	op    string
+	value string
+}
+
+// parseFilters parses --filter expressions
+func parseFilters(exprs []string) ([]filter, error) {
+	filters := make([]filter, len(exprs))
+	for i, expr := range exprs {
+		match := filterExpr.FindStringSubmatch(expr)
+		if match == nil {
+			return nil, fmt.Errorf("invalid filter %q, expected field=value, field!=value or numeric comparison like height>1000", expr)
+		}
+		filters[i] = filter{path: match[1], op: match[2], value: match[3]}
+		if _, err := strconv.ParseFloat(filters[i].value, 64); err != nil && filters[i].op != "=" && filters[i].op != "!=" {
+			return nil, fmt.Errorf("invalid filter %q, %s needs a number", expr, filters[i].op)
+		}
+	}
+	return filters, nil
+}
+
+// filterValue returns elements of list value matching all filters
+func filterValue(value interface{}, filters []filter) (interface{}, error) {
+	// empty path only decodes raw JSON
+	value, err := selectPath(value, "")
+	if err != nil {
+		return nil, err
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("--filter needs list result")
+	}
+	matched := []interface{}{}
+	for _, element := range list {
+		if matchFilters(element, filters) {
+			matched = append(matched, element)
+		}
+	}
+	return matched, nil
+}
+
+// matchFilters reports whether element matches all filters, missing fields never match
+func matchFilters(element interface{}, filters []filter) bool {
+	for _, f := range filters {
+		field, err := selectPath(element, f.path)
+		if err != nil || !f.match(field) {
+			return false
+		}
+	}
+	return true
+}
+
+// match compares field to filter value. Equality compares text of field, other operators compare numbers,
+// numeric strings like amounts count as numbers
+func (f filter) match(field interface{}) bool {
+	text := filterText(field)
+	switch f.op {
+	case "=":
+		return text == f.value
+	case "!=":
+		return text != f.value
+	}
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return false
+	}
+	value, _ := strconv.ParseFloat(f.value, 64)
+	switch f.op {
+	case ">":
+		return number > value
+	case ">=":
+		return number >= value
+	case "<":
+		return number < value
+	default:
+		return number <= value
+	}
+}
+
+// filterText returns field value as compared by filters, strings are used without quotes
+func filterText(field interface{}) string {
+	if text, ok := field.(string); ok {
+		return text
+	}
+	b, _ := json.Marshal(field)
+	return string(b)
+}
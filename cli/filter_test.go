@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseFilters(t *testing.T) {
+	filters, err := parseFilters([]string{"label=coffee", "height>=1000", "note!="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []filter{{"label", "=", "coffee"}, {"height", ">=", "1000"}, {"note", "!=", ""}}
+	if !reflect.DeepEqual(filters, want) {
+		t.Errorf("parseFilters() = %+v, want %+v", filters, want)
+	}
+	for _, expr := range []string{"label", "=coffee", "height>abc"} {
+		if _, err := parseFilters([]string{expr}); err == nil {
+			t.Errorf("parseFilters(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestMatchFilters(t *testing.T) {
+	element := map[string]interface{}{
+		"label":  "coffee",
+		"height": json.Number("1500"),
+		"amount": "0.5",
+		"tx":     map[string]interface{}{"fee": 0.001},
+	}
+	tests := []struct {
+		exprs []string
+		want  bool
+	}{
+		{[]string{"label=coffee"}, true},
+		{[]string{"label!=coffee"}, false},
+		{[]string{"height>1000", "height<2000"}, true},
+		{[]string{"height>1000", "label=tea"}, false},
+		{[]string{"height>=1500", "height<=1500"}, true},
+		// numeric strings like amounts compare as numbers
+		{[]string{"amount<=0.5"}, true},
+		{[]string{"amount>1"}, false},
+		{[]string{"tx.fee<0.01"}, true},
+		// missing fields never match
+		{[]string{"missing=x"}, false},
+		{[]string{"label>1"}, false},
+	}
+	for _, tt := range tests {
+		filters, err := parseFilters(tt.exprs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := matchFilters(element, filters); got != tt.want {
+			t.Errorf("matchFilters(%v) = %v, want %v", tt.exprs, got, tt.want)
+		}
+	}
+}
+
+func TestFilterValue(t *testing.T) {
+	filters, err := parseFilters([]string{"height>100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := []interface{}{
+		map[string]interface{}{"txid": "aa", "height": 100.0},
+		map[string]interface{}{"txid": "bb", "height": 2000.0},
+	}
+	got, err := filterValue(list, filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := list[1:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("filterValue() = %v, want %v", got, want)
+	}
+	if _, err := filterValue(map[string]interface{}{}, filters); err == nil {
+		t.Error("filterValue() of object succeeded, want error")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// health remembers coins whose daemon failed recently, so repeated fan-out calls
+// skip them instead of waiting for full timeout each time. After cooldown the coin
+// is tried again, success clears it. A nil health never skips
+type health struct {
+	cooldown time.Duration
+	mu       sync.Mutex
+	failedAt map[string]time.Time
+}
+
+// newHealth returns health cache skipping failed coins for cooldown, nil if cooldown is 0
+func newHealth(cooldown time.Duration) *health {
+	if cooldown <= 0 {
+		return nil
+	}
+	return &health{cooldown: cooldown, failedAt: map[string]time.Time{}}
+}
+
+// skip returns error describing why coin is skipped, or nil if it should be called
+func (h *health) skip(coin string) error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	failedAt, ok := h.failedAt[coin]
+	if !ok {
+		return nil
+	}
+	since := time.Since(failedAt)
+	if since >= h.cooldown {
+		return nil
+	}
+	return fmt.Errorf("skipped, daemon failed %v ago, retrying in %v",
+		since.Truncate(time.Second), (h.cooldown - since + time.Second - 1).Truncate(time.Second))
+}
+
+// report records outcome of call to coin daemon
+func (h *health) report(coin string, ok bool) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ok {
+		delete(h.failedAt, coin)
+	} else {
+		h.failedAt[coin] = time.Now()
+	}
+}
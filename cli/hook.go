@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+)
+
+// hookCommand returns command running line with system shell
+func hookCommand(line string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", line)
+	}
+	return exec.Command("sh", "-c", line)
+}
+
+// runHook runs --on-result command with result JSON on stdin, and coin and method
+// in BITCART_COIN and BITCART_METHOD environment variables. Hook failure is only a warning,
+// unless --hook-strict is set
+func (s *session) runHook(method string, value interface{}) error {
+	if s.onResult == "" {
+		return nil
+	}
+	b, err := bitcart.Marshal(value, true)
+	if err != nil {
+		return err
+	}
+	cmd := hookCommand(s.onResult)
+	cmd.Stdin = bytes.NewReader(append(b, '\n'))
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), "BITCART_COIN="+s.coin, "BITCART_METHOD="+method)
+	if err = cmd.Run(); err == nil {
+		return nil
+	}
+	err = fmt.Errorf("--on-result hook failed: %w", err)
+	if !s.hookStrict {
+		fmt.Fprintln(stderr, "Warning:", err)
+		return nil
+	}
+	return err
+}
+
+// hookExitCode returns exit code of failed hook, used as process exit code with --hook-strict
+func hookExitCode(err error) int {
+	if exitErr, ok := errors.Unwrap(err).(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+	return exitFailure
+}
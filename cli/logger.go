@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// log levels, in increasing severity
+const (
+	levelDebug = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+// logger writes structured log records as JSON lines.
+// nil logger discards everything, which is the default
+type logger struct {
+	mu    sync.Mutex
+	w     io.Writer
+	level int
+}
+
+// callLog records calls made during the run, set up from --log-level and --log-file
+var callLog *logger
+
+// parseLevel returns log level by name
+func parseLevel(name string) (int, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q, expected one of: %s", name, strings.Join(levelNames, ", "))
+}
+
+// newLogger creates logger writing records of level and above to file, or to stderr if path is "-"
+func newLogger(path, levelName string) (*logger, error) {
+	level, err := parseLevel(levelName)
+	if err != nil {
+		return nil, err
+	}
+	var w io.Writer = os.Stderr
+	if path != "-" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+	return &logger{w: w, level: level}, nil
+}
+
+// log writes record with message and fields if level is enabled
+func (l *logger) log(level int, message string, fields map[string]interface{}) {
+	if l == nil || level < l.level {
+		return
+	}
+	record := make(map[string]interface{}, len(fields)+3)
+	for key, value := range fields {
+		record[key] = value
+	}
+	record["time"] = time.Now().Format(time.RFC3339Nano)
+	record["level"] = levelNames[level]
+	record["msg"] = message
+	b, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(append(b, '\n'))
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fetchMethods calls daemon's help method and returns method names with their help texts.
+// Daemons may return either a list of names or an object of name to help text
+func (s *session) fetchMethods() ([]string, map[string]string, error) {
+	// use a copy, so that request ids of user calls are not affected
+	hs := *s
+	result, err := hs.do("help")
+	if err != nil {
+		return nil, nil, errors.New(s.callError(err))
+	}
+	if result.Error != nil {
+		return nil, nil, fmt.Errorf("help: %s", result.Error.Message)
+	}
+	descriptions := map[string]string{}
+	var names []string
+	switch methods := result.Result.(type) {
+	case []interface{}:
+		for _, method := range methods {
+			names = append(names, fmt.Sprint(method))
+		}
+	case map[string]interface{}:
+		for name, description := range methods {
+			names = append(names, name)
+			descriptions[name] = fmt.Sprint(description)
+		}
+	default:
+		return nil, nil, fmt.Errorf("help: unexpected result %v", result.Result)
+	}
+	sort.Strings(names)
+	return names, descriptions, nil
+}
+
+// listMethods prints sorted method names, optionally with help texts
+func (s *session) listMethods(describe bool) error {
+	names, descriptions, err := s.fetchMethods()
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	if describe && len(descriptions) == 0 {
+		fmt.Fprintln(stderr, "daemon doesn't provide method descriptions")
+	}
+	for _, name := range names {
+		if description, ok := descriptions[name]; ok && describe {
+			fmt.Printf("%s\t%s\n", name, description)
+		} else {
+			fmt.Println(name)
+		}
+	}
+	return nil
+}
+
+// methodsCachePath returns path of cached method list of daemon at url serving coin.
+// The list is keyed by url, as daemons given by --url may run different versions
+func methodsCachePath(coin, url string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(home, ".bitcart", "cache", fmt.Sprintf("%s-%x-methods.json", coin, sum[:6]))
+}
+
+// cachedMethods returns method list of coin's daemon, fetching it on first use or if refresh is set
+func (s *session) cachedMethods(refresh bool) ([]string, error) {
+	path := methodsCachePath(s.coin, s.client.URL)
+	if path == "" {
+		return nil, errors.New("can't locate home directory")
+	}
+	if !refresh {
+		if data, err := ioutil.ReadFile(path); err == nil {
+			var names []string
+			if err := json.Unmarshal(data, &names); err == nil {
+				return names, nil
+			}
+		}
+	}
+	names, _, err := s.fetchMethods()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(names)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// prepareCall returns method to call for method given by user, see resolveCall,
+// confirming dangerous methods with answer read by ask. All call modes go through it
+func (s *session) prepareCall(method string, params interface{}, ask func(prompt string) (string, error)) (string, interface{}, error) {
+	method, params, err := s.resolveCall(method, params)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := s.confirm(method, ask); err != nil {
+		return "", nil, err
+	}
+	return method, params, nil
+}
+
+// resolveCall returns method and params to send for method given by user: alias is expanded,
+// shim from config file applied and method resolved
+func (s *session) resolveCall(method string, params interface{}) (string, interface{}, error) {
+	if alias, ok := s.aliases[method]; ok {
+		method = alias
+	}
+	method, params, err := applyShim(s.shims, method, params)
+	if err != nil {
+		return "", nil, fail(err, exitFailure)
+	}
+	method, err = s.resolveMethod(method)
+	if err != nil {
+		return "", nil, err
+	}
+	return method, params, nil
+}
+
+// resolveMethod returns name of method known to daemon with --check-methods, expanding unambiguous prefix
+// of it unless expansion is disabled. Ambiguous prefixes fail listing the candidates. Unknown methods cause
+// a warning, or fail in strict mode. Validation is skipped if method list can't be fetched
+func (s *session) resolveMethod(method string) (string, error) {
+	if !s.checkMethods || s.dryRun || s.mock {
+		return method, nil
+	}
+	names, err := s.cachedMethods(s.refreshMethods)
+	// method list is only refreshed once per run
+	s.refreshMethods = false
+	if err != nil {
+		if s.verbose > 0 {
+			fmt.Fprintln(stderr, "* skipping method validation:", err)
+		}
+		return method, nil
+	}
+	var candidates []string
+	for _, name := range names {
+		if name == method {
+			return method, nil
+		}
+		if strings.HasPrefix(name, method) {
+			candidates = append(candidates, name)
+		}
+	}
+	if !s.noExpand && len(candidates) == 1 {
+		if s.verbose > 0 {
+			fmt.Fprintf(stderr, "* method: %s expanded to %s\n", method, candidates[0])
+		}
+		return candidates[0], nil
+	}
+	if !s.noExpand && len(candidates) > 1 {
+		sort.Strings(candidates)
+		return "", fail(fmt.Sprintf("ambiguous method %q, candidates: %s", method, strings.Join(candidates, ", ")), exitMethodNotFound)
+	}
+	if s.strict {
+		return "", fail(fmt.Sprintf("unknown method %q, run with --refresh-methods if daemon was updated", method), exitMethodNotFound)
+	}
+	fmt.Fprintf(stderr, "Warning: unknown method %q\n", method)
+	return method, nil
+}
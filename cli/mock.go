@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// mockResponse is canned response for method from --mock file
+type mockResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// loadMock reads {method: {"result": ...} or {"error": ...}} object from file
+func loadMock(path string) (map[string]mockResponse, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var responses map[string]mockResponse
+	if err := json.Unmarshal(data, &responses); err != nil {
+		return nil, fmt.Errorf("parsing mock file %s: %v", path, err)
+	}
+	for method, response := range responses {
+		if response.Result == nil && response.Error == nil {
+			return nil, fmt.Errorf("parsing mock file %s: %s has neither result nor error", path, method)
+		}
+	}
+	return responses, nil
+}
+
+// mockTransport is http transport answering JSON-RPC requests from mock file without network access.
+// Methods missing from the file get "method not found" error
+type mockTransport struct {
+	responses map[string]mockResponse
+	verbose   int
+}
+
+func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var request struct {
+		Method string          `json:"method"`
+		ID     json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, err
+	}
+	response, ok := t.responses[request.Method]
+	if !ok {
+		message, _ := json.Marshal(fmt.Sprintf("method %s not found in mock file", request.Method))
+		response.Error = json.RawMessage(`{"code": -32601, "message": ` + string(message) + `}`)
+	}
+	if t.verbose > 0 {
+		fmt.Fprintf(stderr, "* mock: answering %s without calling daemon\n", request.Method)
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      request.ID,
+		"result":  response.Result,
+		"error":   response.Error,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+)
+
+// stream calls method and prints elements of array result one per line as they are decoded from response.
+// Other results are printed as a single line
+func (s *session) stream(method string, params interface{}) error {
+	var out io.Writer = os.Stdout
+	if s.output.file != "" {
+		f, err := os.Create(s.output.file)
+		if err != nil {
+			return fail(err, exitFailure)
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	writeLine := func(value json.RawMessage) error {
+		if s.output.redact != nil {
+			redacted, err := redactValue(value, s.output.redact)
+			if err != nil {
+				return err
+			}
+			if value, err = json.Marshal(redacted); err != nil {
+				return err
+			}
+		}
+		var line bytes.Buffer
+		if err := json.Compact(&line, value); err != nil {
+			return err
+		}
+		b := line.Bytes()
+		if s.output.color && s.output.file == "" {
+			b = colorizeJSON(b)
+		}
+		w.Write(b)
+		return w.WriteByte('\n')
+	}
+	request := s.newRequest(method, params)
+	start := time.Now()
+	ctx, cancel := s.callContext()
+	defer cancel()
+	result, err := s.client.StreamContext(ctx, request, writeLine)
+	s.logCall(request, time.Since(start), result, err)
+	if err == nil && result.Error == nil && result.Result != nil {
+		var b []byte
+		if b, err = s.output.marshal(result.Result); err == nil {
+			err = writeLine(b)
+		}
+	}
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		return s.failCall(err)
+	}
+	if result.Error != nil {
+		return s.printRPCError(result.Error, rpcError{result.Error, s.lastID})
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+	"github.com/urfave/cli"
+)
+
+// outputOptions control how results are printed
+type outputOptions struct {
+	file    string
+	compact bool
+	raw     bool
+	table   bool
+	csv     bool
+	yaml    bool
+	color   bool
+	ndjson  bool
+	// selectPath extracts part of result before printing
+	selectPath string
+	// filters keep matching elements of list result, after selectPath
+	filters []filter
+	// count prints number of elements of result instead of it
+	count bool
+	// withMeta wraps result with call details
+	withMeta bool
+	// maxOutput limits bytes printed to stdout, 0 is unlimited
+	maxOutput int
+	// autofile is size in bytes above which result is written to file instead of stdout, 0 disables it.
+	// The file is output file if set, otherwise a temporary one
+	autofile int
+	// template renders result instead of other formats if set
+	template *template.Template
+	// redact are lowercased keys whose values are hidden at any depth
+	redact map[string]bool
+}
+
+// outputModes maps BITCART_OUTPUT values to output flags they stand for, pretty is indented JSON
+var outputModes = map[string]string{"pretty": "", "compact": "compact", "raw": "raw", "yaml": "yaml", "table": "table", "csv": "csv"}
+
+// defaultOutputFlag returns output flag applied when no output format flag is given:
+// the one named by BITCART_OUTPUT, otherwise compact when stdout isn't a terminal.
+// Results written to --output file stay indented unless BITCART_OUTPUT says otherwise
+func defaultOutputFlag(c *cli.Context) (string, error) {
+	for _, name := range []string{"compact", "raw", "table", "csv", "yaml", "template", "template-file", "prom"} {
+		if c.IsSet(name) {
+			return "", nil
+		}
+	}
+	if mode := os.Getenv("BITCART_OUTPUT"); mode != "" {
+		flag, ok := outputModes[strings.ToLower(mode)]
+		if !ok {
+			modes := make([]string, 0, len(outputModes))
+			for mode := range outputModes {
+				modes = append(modes, mode)
+			}
+			sort.Strings(modes)
+			return "", fmt.Errorf("invalid BITCART_OUTPUT %q, expected one of %s", mode, strings.Join(modes, ", "))
+		}
+		return flag, nil
+	}
+	if c.String("output") == "" && !isTerminal(os.Stdout) {
+		return "compact", nil
+	}
+	return "", nil
+}
+
+// marshal encodes value as JSON according to output options. Raw JSON is returned untouched
+func (o outputOptions) marshal(value interface{}) ([]byte, error) {
+	return bitcart.Marshal(value, o.compact)
+}
+
+// format encodes result using selected output format
+func (o outputOptions) format(value interface{}) ([]byte, error) {
+	if o.template != nil {
+		return renderTemplate(o.template, value)
+	}
+	if o.csv {
+		return renderCSV(value)
+	}
+	if o.table {
+		if rows, ok := tableRows(value); ok {
+			return renderTable(rows), nil
+		}
+		fmt.Fprintln(stderr, "Warning: result is not a list of flat objects, printing JSON")
+	}
+	if o.yaml {
+		return marshalYAML(value)
+	}
+	return o.marshal(value)
+}
+
+// isJSON reports whether value is printed as JSON with current options
+func (o outputOptions) isJSON(value interface{}) bool {
+	if o.template != nil || o.csv {
+		return false
+	}
+	if _, ok := value.(json.RawMessage); ok {
+		return true
+	}
+	if o.table {
+		if _, ok := tableRows(value); ok {
+			return false
+		}
+	}
+	return !o.yaml
+}
+
+// write formats value and prints it to stdout or output file
+func (o outputOptions) write(value interface{}) error {
+	if o.redact != nil {
+		var err error
+		if value, err = redactValue(value, o.redact); err != nil {
+			return fail(err, exitFailure)
+		}
+	}
+	b, err := o.format(value)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	if o.autofile > 0 && o.file == "" {
+		return o.writeAuto(value, b)
+	}
+	if o.file != "" {
+		if err := ioutil.WriteFile(o.file, append(b, '\n'), 0644); err != nil {
+			return fail(err, exitFailure)
+		}
+		return nil
+	}
+	size := len(b)
+	truncated := o.maxOutput > 0 && size > o.maxOutput
+	if truncated {
+		b = truncate(b, o.maxOutput)
+	}
+	shown := len(b)
+	if o.color && o.isJSON(value) {
+		b = colorizeJSON(b)
+	}
+	fmt.Println(string(b))
+	if truncated {
+		fmt.Println("...(truncated)")
+		fmt.Fprintf(stderr, "Warning: printed %d of %d bytes, use --output FILE to save full result\n", shown, size)
+	}
+	return nil
+}
+
+// writeAuto prints formatted value b unless it is bigger than autofile, then it is written to a temporary
+// file and only its path is printed to stderr. Results go to output file regardless of size, see write
+func (o outputOptions) writeAuto(value interface{}, b []byte) error {
+	if len(b) <= o.autofile {
+		o.autofile = 0
+		return o.write(value)
+	}
+	f, err := ioutil.TempFile("", "bitcart-result-*"+o.extension(value))
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	f.Close()
+	path := f.Name()
+	if err := ioutil.WriteFile(path, append(b, '\n'), 0644); err != nil {
+		return fail(err, exitFailure)
+	}
+	fmt.Fprintf(stderr, "result written to %s (%d bytes)\n", path, len(b))
+	return nil
+}
+
+// extension returns file name extension of value printed with current options
+func (o outputOptions) extension(value interface{}) string {
+	switch {
+	case o.isJSON(value):
+		return ".json"
+	case o.csv:
+		return ".csv"
+	case o.yaml:
+		return ".yaml"
+	}
+	return ".txt"
+}
+
+// truncate cuts b to at most n bytes without splitting UTF-8 characters
+func truncate(b []byte, n int) []byte {
+	for n > 0 && !utf8.RuneStart(b[n]) {
+		n--
+	}
+	return b[:n]
+}
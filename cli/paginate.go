@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/MrNaif2018/jsonrpc"
+)
+
+// defaultPageSize is number of elements requested per page unless --page-size is set
+const defaultPageSize = 100
+
+// paginate calls method with offset and count params added to params for successive pages,
+// until a page has fewer than pageSize elements. Daemons ignoring the params would make it loop forever,
+// so it also stops with a warning at a page bigger than pageSize or repeating the previous one.
+// Response holds elements of all pages, or the first failed page
+func (s *session) paginate(method string, params interface{}, pageSize int) (*jsonrpc.RPCResponse, error) {
+	named := map[string]interface{}{}
+	switch params := params.(type) {
+	case map[string]interface{}:
+		for key, value := range params {
+			named[key] = value
+		}
+	case []interface{}:
+		if len(params) > 0 {
+			return nil, errors.New("--paginate needs named params like key=value, offset and count are added to them")
+		}
+	case nil:
+	default:
+		return nil, errors.New("--paginate needs named params like key=value, offset and count are added to them")
+	}
+	elements := []interface{}{}
+	var previous []interface{}
+	for offset := 0; ; offset += pageSize {
+		if interrupted() {
+			return nil, errors.New("interrupted")
+		}
+		named["offset"], named["count"] = offset, pageSize
+		result, err := s.do(method, named)
+		if err != nil {
+			return nil, errors.New(s.callError(err))
+		}
+		if result.Error != nil {
+			return result, nil
+		}
+		page, ok := result.Result.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("--paginate needs list result, %s returned %T", method, result.Result)
+		}
+		if s.verbose > 0 {
+			fmt.Fprintf(stderr, "* page at offset %d: %d elements\n", offset, len(page))
+		}
+		if previous != nil && reflect.DeepEqual(page, previous) {
+			fmt.Fprintf(stderr, "Warning: %s returned the same page at offset %d, daemon ignores offset param\n", method, offset)
+			result.Result = elements
+			return result, nil
+		}
+		elements = append(elements, page...)
+		if len(page) > pageSize {
+			fmt.Fprintf(stderr, "Warning: %s returned %d elements for count %d, daemon ignores count param\n", method, len(page), pageSize)
+		}
+		if len(page) != pageSize {
+			result.Result = elements
+			return result, nil
+		}
+		previous = page
+	}
+}
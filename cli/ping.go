@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// pingMethod is cheap RPC method which doesn't need a wallet
+const pingMethod = "version"
+
+// ping calls pingMethod and returns round trip time. Any JSON-RPC response means daemon is reachable,
+// even an error one, only transport and HTTP failures are returned
+func (s *session) ping() (time.Duration, error) {
+	start := time.Now()
+	result, err := s.do(pingMethod)
+	elapsed := time.Since(start)
+	if err != nil {
+		return elapsed, errors.New(s.callError(err))
+	}
+	if result.Error != nil && s.verbose > 0 {
+		fmt.Fprintf(stderr, "* %s failed, daemon responded: %s\n", pingMethod, result.Error.Message)
+	}
+	return elapsed, nil
+}
+
+// runPing pings daemon of each coin and prints latency, failing if any of them didn't respond.
+// Coins which failed recently are skipped according to h
+func runPing(coins []string, newCoinSession func(coin string) (*session, error), h *health) error {
+	failed := false
+	for _, coin := range coins {
+		err := h.skip(coin)
+		if err == nil {
+			var s *session
+			if s, err = newCoinSession(coin); err == nil {
+				var elapsed time.Duration
+				elapsed, err = s.ping()
+				h.report(coin, err == nil)
+				if err == nil {
+					fmt.Printf("%s\tok\t%dms\n", coin, elapsed.Milliseconds())
+					continue
+				}
+			}
+		}
+		failed = true
+		fmt.Printf("%s\tfailed\t%v\n", coin, err)
+	}
+	if failed {
+		return cli.NewExitError("", exitFailure)
+	}
+	return nil
+}
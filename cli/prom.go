@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// metricNamePattern matches valid Prometheus metric names
+var metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// invalidMetricChars are characters replaced in metric names derived from method names
+var invalidMetricChars = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+
+// metricName returns metric name printed by --prom unless --metric-name is set:
+// method name without get prefix, like bitcart_balance for getbalance
+func metricName(method string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(method, "get_"), "get")
+	if name == "" {
+		name = method
+	}
+	return "bitcart_" + invalidMetricChars.ReplaceAllString(name, "_")
+}
+
+// metricValue returns sample value of numeric result, numeric strings like balances are accepted as well
+func metricValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	case int:
+		return strconv.Itoa(v), true
+	case json.Number:
+		return v.String(), true
+	case string:
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// promLabels renders labels as {name="value",...}, escaping values
+func promLabels(labels [][2]string) string {
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	parts := make([]string, len(labels))
+	for i, label := range labels {
+		value := escape.Replace(label[1])
+		parts[i] = label[0] + `="` + value + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// renderMetric renders numeric value as metric line, or flat object of numeric values as line per key
+// labelled with field. Other values are an error
+func renderMetric(name string, labels [][2]string, value interface{}) ([]byte, error) {
+	if sample, ok := metricValue(value); ok {
+		return []byte(name + promLabels(labels) + " " + sample + "\n"), nil
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok || len(object) == 0 {
+		return nil, fmt.Errorf("--prom needs numeric result, got %s", describeValue(value))
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b []byte
+	for _, key := range keys {
+		sample, ok := metricValue(object[key])
+		if !ok {
+			return nil, fmt.Errorf("--prom needs numeric result, %s is %s, use --select to pick numeric field", key, describeValue(object[key]))
+		}
+		b = append(b, name+promLabels(append(labels, [2]string{"field", key}))+" "+sample+"\n"...)
+	}
+	return b, nil
+}
+
+// describeValue names JSON type of value for errors
+func describeValue(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "non-numeric string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// writeMetric prints result as Prometheus metric lines labelled with coin and wallet, to stdout or output file
+func (s *session) writeMetric(method string, value interface{}) error {
+	name := s.metricName
+	if name == "" {
+		name = metricName(method)
+	}
+	labels := [][2]string{{"coin", s.coin}}
+	if s.wallet != "" {
+		labels = append(labels, [2]string{"wallet", s.wallet})
+	}
+	b, err := renderMetric(name, labels, value)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	if s.output.file != "" {
+		if err := ioutil.WriteFile(s.output.file, b, 0644); err != nil {
+			return fail(err, exitFailure)
+		}
+		return nil
+	}
+	fmt.Print(string(b))
+	return nil
+}
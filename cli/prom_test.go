@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMetricName(t *testing.T) {
+	tests := map[string]string{
+		"getbalance":  "bitcart_balance",
+		"get_tx_size": "bitcart_tx_size",
+		"get":         "bitcart_get",
+		"fee-rate":    "bitcart_fee_rate",
+	}
+	for method, want := range tests {
+		if got := metricName(method); got != want {
+			t.Errorf("metricName(%q) = %q, want %q", method, got, want)
+		}
+	}
+}
+
+func TestRenderMetric(t *testing.T) {
+	labels := [][2]string{{"coin", "btc"}, {"wallet", `my "w"`}}
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{1.5, `bitcart_balance{coin="btc",wallet="my \"w\""} 1.5` + "\n"},
+		{json.Number("12"), `bitcart_balance{coin="btc",wallet="my \"w\""} 12` + "\n"},
+		{"0.001", `bitcart_balance{coin="btc",wallet="my \"w\""} 0.001` + "\n"},
+		{
+			map[string]interface{}{"unconfirmed": "0", "confirmed": "1.5"},
+			`bitcart_balance{coin="btc",wallet="my \"w\"",field="confirmed"} 1.5` + "\n" +
+				`bitcart_balance{coin="btc",wallet="my \"w\"",field="unconfirmed"} 0` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		got, err := renderMetric("bitcart_balance", labels, tt.value)
+		if err != nil {
+			t.Errorf("renderMetric(%v) error: %v", tt.value, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("renderMetric(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+	for _, value := range []interface{}{nil, "abc", true, []interface{}{1.0}, map[string]interface{}{}, map[string]interface{}{"a": "x"}} {
+		if got, err := renderMetric("bitcart_balance", labels, value); err == nil {
+			t.Errorf("renderMetric(%v) = %q, want error", value, got)
+		}
+	}
+}
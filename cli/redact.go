@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// redactMask replaces values of redacted fields
+const redactMask = "***"
+
+// secretFields are keys redacted with --redact-secrets
+var secretFields = []string{"seed", "xprv", "privkey", "private_key", "passphrase", "password", "mnemonic"}
+
+// redactFields returns set of lowercased keys redacted by --redact and --redact-secrets, nil if there are none
+func redactFields(fields []string, secrets bool) map[string]bool {
+	if secrets {
+		fields = append(fields, secretFields...)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	redacted := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		redacted[strings.ToLower(field)] = true
+	}
+	return redacted
+}
+
+// redactValue returns value with values of keys in fields replaced by redactMask at any depth,
+// ignoring case of keys. Value goes through JSON, so it works for any printed type
+func redactValue(value interface{}, fields map[string]bool) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return redactFieldsOf(decoded, fields), nil
+}
+
+// redactFieldsOf replaces values of fields in decoded JSON value in place
+func redactFieldsOf(value interface{}, fields map[string]bool) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, element := range value {
+			if fields[strings.ToLower(key)] {
+				value[key] = redactMask
+			} else {
+				value[key] = redactFieldsOf(element, fields)
+			}
+		}
+	case []interface{}:
+		for i, element := range value {
+			value[i] = redactFieldsOf(element, fields)
+		}
+	}
+	return value
+}
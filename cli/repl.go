@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+// historyLimit is maximum number of lines kept in REPL history file
+const historyLimit = 1000
+
+// secretArg matches REPL arguments setting secrets inline, such lines are not saved to history
+var secretArg = regexp.MustCompile(`(?i)(password|passphrase|token|secret|seed|mnemonic|key)\w*=`)
+
+// defaultHistoryPath returns path to REPL history file in user's home directory
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".bitcart", "history")
+}
+
+// splitLine splits REPL input into words, respecting single and double quotes
+func splitLine(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// runREPL reads method calls from stdin line by line, reusing a single client.
+// On terminals lines are edited with readline and saved to history file, unless it is empty
+func (s *session) runREPL(raw bool, historyFile string) error {
+	prompt := s.coin
+	if s.wallet != "" {
+		prompt += "/" + s.wallet
+	}
+	prompt += "> "
+	readLine, saveLine, closeInput, err := replInput(historyFile)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+	for {
+		line, err := readLine(prompt)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		words, err := splitLine(line)
+		if err != nil {
+			reportError(errorInfo{Type: "error", Message: err.Error()})
+			continue
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if !secretArg.MatchString(line) {
+			saveLine(line)
+		}
+		if words[0] == "exit" || words[0] == "quit" {
+			return nil
+		}
+		params, err := parseArgs(words[1:], raw, "", nil)
+		if err != nil {
+			reportError(errorInfo{Type: "error", Message: err.Error()})
+			continue
+		}
+		method, params, err := s.prepareCall(words[0], params, readLine)
+		if err != nil {
+			continue
+		}
+		// errors are already printed, session continues
+		s.call(method, params)
+	}
+}
+
+// replInput returns functions reading next REPL line after prompt, saving it to history and releasing terminal.
+// readLine returns io.EOF when input ends or session is interrupted
+func replInput(historyFile string) (readLine func(prompt string) (string, error), saveLine func(string), closeInput func(), err error) {
+	if !isTerminal(os.Stdin) {
+		return scannerInput()
+	}
+	if historyFile != "" {
+		if err := os.MkdirAll(filepath.Dir(historyFile), 0700); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	rl, err := readline.NewEx(&readline.Config{
+		HistoryFile:            historyFile,
+		HistoryLimit:           historyLimit,
+		DisableAutoSaveHistory: true,
+		HistorySearchFold:      true,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// closing readline unblocks reading when interrupted by signal
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-shutdown.Done():
+			rl.Close()
+		case <-done:
+		}
+	}()
+	readLine = func(prompt string) (string, error) {
+		rl.SetPrompt(prompt)
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt || interrupted() {
+			return "", io.EOF
+		}
+		return line, err
+	}
+	saveLine = func(line string) {
+		rl.SaveHistory(line)
+	}
+	closeInput = func() {
+		close(done)
+		rl.Close()
+	}
+	return readLine, saveLine, closeInput, nil
+}
+
+// scannerInput reads REPL lines from non-terminal stdin, without history
+func scannerInput() (readLine func(prompt string) (string, error), saveLine func(string), closeInput func(), err error) {
+	// lines are read in background, so interrupt is noticed while waiting for input
+	lines := make(chan string)
+	scanner := bufio.NewScanner(os.Stdin)
+	go func() {
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	readLine = func(prompt string) (string, error) {
+		fmt.Print(prompt)
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				fmt.Println()
+				if err := scanner.Err(); err != nil {
+					return "", err
+				}
+				return "", io.EOF
+			}
+			return line, nil
+		case <-shutdown.Done():
+			fmt.Println()
+			return "", io.EOF
+		}
+	}
+	return readLine, func(string) {}, func() {}, nil
+}
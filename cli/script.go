@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// runScript executes calls from script file, or stdin if path is "-", one "method args" line per call,
+// in the same syntax as REPL. Blank lines and lines starting with # are skipped.
+// Failed calls are reported and the rest still run, unless --stop-on-error is set
+func (s *session) runScript(path string, raw bool) error {
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fail(err, exitFailure)
+		}
+		defer file.Close()
+		input = file
+	}
+	var failure error
+	scanner := bufio.NewScanner(input)
+	for number := 1; scanner.Scan() && !interrupted(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		err := s.runScriptLine(line, raw)
+		if err == nil {
+			continue
+		}
+		if message := err.Error(); message != "" {
+			// syntax errors aren't printed yet, call errors are
+			reportError(errorInfo{Type: "error", Message: fmt.Sprintf("%s:%d: %s", path, number, message)})
+			err = cli.NewExitError("", exitFailure)
+		}
+		if failure == nil {
+			failure = err
+		}
+		if s.stopOnError {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fail(err, exitFailure)
+	}
+	return failure
+}
+
+// runScriptLine parses and calls single script line
+func (s *session) runScriptLine(line string, raw bool) error {
+	words, err := splitLine(line)
+	if err != nil {
+		return err
+	}
+	params, err := parseArgs(words[1:], raw, "", nil)
+	if err != nil {
+		return err
+	}
+	method, params, err := s.prepareCall(words[0], params, askStdin)
+	if err != nil {
+		return err
+	}
+	return s.call(method, params)
+}
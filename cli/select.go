@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parsePath splits dotted path like result.items[0].txid into keys.
+// Leading "$" or "result" refers to the result itself and is optional
+func parsePath(path string) []string {
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	var keys []string
+	for _, key := range strings.Split(path, ".") {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > 0 && (keys[0] == "$" || keys[0] == "result") {
+		keys = keys[1:]
+	}
+	return keys
+}
+
+// selectPath returns part of value found by dotted path, list elements are selected by index
+func selectPath(value interface{}, path string) (interface{}, error) {
+	if raw, ok := value.(json.RawMessage); ok {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+	}
+	for i, key := range parsePath(path) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if value, ok = v[key]; !ok {
+				return nil, fmt.Errorf("path %q not found: no key %q", path, key)
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, fmt.Errorf("path %q not found: no index %q in list of %d", path, key, len(v))
+			}
+			value = v[index]
+		default:
+			return nil, fmt.Errorf("path %q not found: %s is not an object or list", path, strings.Join(parsePath(path)[:i], "."))
+		}
+	}
+	return value, nil
+}
+
+// countValue returns number of elements of list or keys of object
+func countValue(value interface{}) (int, error) {
+	// empty path only decodes raw JSON
+	value, err := selectPath(value, "")
+	if err != nil {
+		return 0, err
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		return len(v), nil
+	case map[string]interface{}:
+		return len(v), nil
+	}
+	return 0, fmt.Errorf("result is not a list or object, it can't be counted")
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+	"github.com/MrNaif2018/jsonrpc"
+	"github.com/urfave/cli"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// session holds state shared by all calls made during one run
+type session struct {
+	client *bitcart.Client
+	coin   string
+	wallet string
+	// user is daemon user, shown in authentication errors
+	user    string
+	timeout time.Duration
+	output  outputOptions
+	// recorder receives responses of session calls, copies of session made for concurrent calls need their own
+	recorder *responseRecord
+	verbose  int
+	dryRun   bool
+	// id of next request and of the last sent one
+	nextID int
+	lastID int
+	// notify sends calls as notifications without id, see notification
+	notify bool
+	// pageSize is number of elements per page with --paginate, 0 disables paging
+	pageSize int
+	// duration of the last call
+	lastDuration time.Duration
+	// callTimeout limits each call including retries, 0 is unlimited
+	callTimeout time.Duration
+	// maximum number of calls in flight in batch and fan-out modes
+	concurrency int
+	// stopOnError skips remaining batch and fan-out calls after a failure
+	stopOnError bool
+	// keepGoing runs remaining chained methods after a failure
+	keepGoing bool
+	// mock answers calls from file
+	mock bool
+	// confirmation of dangerous methods
+	confirmation confirmation
+	// noWallet omits xpub field from requests
+	noWallet bool
+	// schema validates results if set
+	schema *gojsonschema.Schema
+	// assertions are checked against results
+	assertions []assertion
+	// cache serves repeated read-only calls, nil unless --cache is set
+	cache *responseCache
+	// failOnEmpty makes empty results an error
+	failOnEmpty bool
+	// onResult is shell command receiving each successful result, see runHook
+	onResult   string
+	hookStrict bool
+	// credentialSets are named credentials from config file, used by single calls, see withCredentials
+	credentialSets map[string]credentialConfig
+	// secretBody keeps request bodies of session calls out of verbose output and traces, see withSecretBody
+	secretBody bool
+	// aliases map short names to method names and shims map invocations to daemon methods, see resolveCall
+	aliases map[string]string
+	shims   map[string]shimConfig
+	// method validation settings, methods are only checked against daemon's method list with checkMethods,
+	// noExpand disables expanding method prefixes
+	checkMethods   bool
+	strict         bool
+	refreshMethods bool
+	noExpand       bool
+	// unlockMethod is called with unlockPassword, or wallet password from unlockPasswords, when wallet is locked
+	unlockMethod    string
+	unlockPassword  string
+	unlockPasswords map[string]string
+	// prom prints numeric results as Prometheus metric lines named metricName, derived from method if empty
+	prom       bool
+	metricName string
+	// abortOnInterrupt calls cancelMethod, or method mapped in cancelMethods, when call is interrupted
+	abortOnInterrupt bool
+	cancelMethod     string
+	cancelMethods    map[string]string
+}
+
+// newOutputOptions loads output settings from flags
+func newOutputOptions(c *cli.Context) outputOptions {
+	return outputOptions{
+		file:       c.String("output"),
+		compact:    c.Bool("compact"),
+		raw:        c.Bool("raw"),
+		table:      c.Bool("table"),
+		csv:        c.Bool("csv"),
+		yaml:       c.Bool("yaml"),
+		color:      useColor(c.Bool("color"), c.Bool("no-color")),
+		ndjson:     c.Bool("ndjson"),
+		selectPath: c.String("select"),
+		count:      c.Bool("count"),
+		withMeta:   c.Bool("with-meta"),
+		maxOutput:  c.Int("max-output"),
+		autofile:   c.Int("autofile"),
+		redact:     redactFields(splitList(c.StringSlice("redact")), c.Bool("redact-secrets")),
+	}
+}
+
+// newSession resolves flags and config file values into rpc client and call settings for coin
+func newSession(c *cli.Context, coin string, coins map[string]string, cfg *config) (*session, error) {
+	// resolve daemon URL, explicit URL was already merged into coins
+	url, ok := coins[coin]
+	if !ok {
+		return nil, fmt.Errorf("unknown coin %q; known coins: %s", coin, strings.Join(bitcart.SortedCoins(coins), ", "))
+	}
+	creds, err := resolveCredentials(c, coin, cfg)
+	if err != nil {
+		return nil, err
+	}
+	wallet, user, password, token := creds.wallet, creds.user, creds.password, creds.token
+	timeout := time.Duration(c.Int("timeout")) * time.Second
+	mockFile := c.String("mock")
+	socket := ""
+	if mockFile == "" {
+		socket, _, _ = bitcart.UnixSocketURL(url)
+	}
+	fallbacks := fallbackURLs[coin]
+	if mockFile != "" {
+		fallbacks = nil
+	}
+	// fallbacks share http client, which dials socket of primary daemon
+	for _, fallbackURL := range fallbacks {
+		if _, _, ok := bitcart.UnixSocketURL(fallbackURL); ok || socket != "" {
+			return nil, fmt.Errorf("fallback URLs of %s can't be used with Unix sockets", coin)
+		}
+	}
+	// initialize rpc client
+	httpClient, err := bitcart.NewHTTPClient(bitcart.HTTPOptions{
+		Timeout:    timeout,
+		Insecure:   c.Bool("insecure"),
+		Proxy:      c.String("proxy"),
+		ClientCert: c.String("client-cert"),
+		ClientKey:  c.String("client-key"),
+		CACerts:    c.StringSlice("ca-cert"),
+		Socket:     socket,
+		IPVersion:  c.Int("ip-version"),
+		// connections are only reused by modes doing many calls
+		KeepAlive: makesManyCalls(c),
+	})
+	if err != nil {
+		return nil, err
+	}
+	verbose := 0
+	if c.Bool("vv") {
+		verbose = 2
+	} else if c.Bool("verbose") {
+		verbose = 1
+	}
+	if mockFile != "" {
+		responses, err := loadMock(mockFile)
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Transport = &mockTransport{responses: responses, verbose: verbose}
+	}
+	if verbose > 0 {
+		if mockFile != "" {
+			fmt.Fprintln(stderr, "* mock: responses are read from", mockFile+", daemon is not called")
+		}
+		fmt.Fprintln(stderr, "* url:", url)
+		httpClient.Transport = &verboseTransport{transport: httpClient.Transport, level: verbose}
+	}
+	// trace sees requests as sent, with signature
+	if callTrace != nil {
+		httpClient.Transport = &traceTransport{transport: httpClient.Transport, trace: callTrace, statusOnly: c.Bool("ndjson")}
+	}
+	// signature is computed over body as sent, after xpub is removed
+	if secret := c.String("hmac-secret"); secret != "" {
+		if httpClient.Transport, err = newSignTransport(httpClient.Transport, secret, c.String("hmac-algorithm"), c.String("hmac-header")); err != nil {
+			return nil, err
+		}
+	}
+	// jsonrpc always sends xpub field, so it is removed from request body
+	if c.Bool("no-wallet") {
+		httpClient.Transport = &noWalletTransport{transport: httpClient.Transport}
+	}
+	// response is recorded for raw output, --with-meta and for reporting HTTP errors.
+	// Only status is recorded when streaming, as body would hold the whole response in memory
+	recorder := &responseRecord{}
+	httpClient.Transport = &bodyRecorder{transport: httpClient.Transport, statusOnly: c.Bool("ndjson"), record: recorder}
+	headers, err := bitcart.ParseHeaders(c.StringSlice("header"))
+	if err != nil {
+		return nil, err
+	}
+	rpcVersion := resolveRPCVersion(c, coin, cfg)
+	client, err := bitcart.NewClient(url, bitcart.Options{
+		Wallet:     wallet,
+		User:       user,
+		Password:   password,
+		Token:      token,
+		Headers:    headers,
+		UserAgent:  c.String("user-agent"),
+		HTTPClient: httpClient,
+		Retries:    c.Int("retry"),
+		RPCVersion: rpcVersion,
+		Fallbacks:  fallbacks,
+	})
+	if err != nil {
+		return nil, err
+	}
+	client.OnFailover = func(request *jsonrpc.RPCRequest, from, to string, err error) {
+		if verbose > 0 {
+			fmt.Fprintf(stderr, "* %s is unreachable, switching to %s: %v\n", from, to, err)
+		}
+		callLog.log(levelInfo, "failover", map[string]interface{}{
+			"method": request.Method,
+			"id":     request.ID,
+			"from":   from,
+			"to":     to,
+			"error":  err.Error(),
+		})
+	}
+	client.OnRetry = func(request *jsonrpc.RPCRequest, attempt int, delay time.Duration, err error) {
+		if verbose > 0 {
+			fmt.Fprintf(stderr, "* retry %d/%d in %v: %v\n", attempt, client.Retries, delay, err)
+		}
+		callLog.log(levelDebug, "retry", map[string]interface{}{
+			"method":  request.Method,
+			"id":      request.ID,
+			"attempt": attempt,
+			"error":   err.Error(),
+		})
+	}
+	var schema *gojsonschema.Schema
+	if path := c.String("validate"); path != "" {
+		if schema, err = loadSchema(path); err != nil {
+			return nil, err
+		}
+	}
+	output := newOutputOptions(c)
+	if output.filters, err = parseFilters(c.StringSlice("filter")); err != nil {
+		return nil, err
+	}
+	assertions, err := parseAssertions(c.StringSlice("assert"))
+	if err != nil {
+		return nil, err
+	}
+	// raw output prints recorded response body, which cached calls don't have
+	if c.Duration("cache") > 0 && output.raw {
+		return nil, errors.New("--cache can't be combined with --raw")
+	}
+	if output.count && output.ndjson {
+		return nil, errors.New("--count can't be combined with --ndjson")
+	}
+	if c.String("on-result") != "" && output.ndjson {
+		return nil, errors.New("--on-result can't be combined with --ndjson")
+	}
+	// streamed elements are printed as they arrive, so result checks and transformations can't apply
+	if output.ndjson {
+		for _, name := range []string{"assert", "fail-on-empty", "validate", "filter", "select"} {
+			if c.IsSet(name) {
+				return nil, fmt.Errorf("--%s can't be combined with --ndjson", name)
+			}
+		}
+	}
+	pageSize := 0
+	if c.Bool("paginate") {
+		if pageSize = c.Int("page-size"); pageSize <= 0 {
+			return nil, errors.New("--page-size must be positive")
+		}
+		// raw output and streaming only see the last page
+		for _, name := range []string{"raw", "ndjson", "no-id"} {
+			if c.Bool(name) {
+				return nil, fmt.Errorf("--paginate can't be combined with --%s", name)
+			}
+		}
+	}
+	// notifications have no result to print or check
+	if c.Bool("no-id") {
+		for _, name := range []string{"ndjson", "with-meta", "assert", "on-result", "watch", "batch"} {
+			if c.IsSet(name) {
+				return nil, fmt.Errorf("--no-id can't be combined with --%s", name)
+			}
+		}
+	}
+	if c.Bool("prom") {
+		for _, name := range []string{"raw", "ndjson", "no-id", "with-meta", "table", "csv", "yaml", "template", "template-file"} {
+			if c.IsSet(name) {
+				return nil, fmt.Errorf("--prom can't be combined with --%s", name)
+			}
+		}
+		if name := c.String("metric-name"); name != "" && !metricNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid --metric-name %q, metric names are letters, digits, _ and : not starting with digit", name)
+		}
+	} else if c.IsSet("metric-name") {
+		return nil, errors.New("--metric-name needs --prom")
+	}
+	if c.IsSet("cancel-method") && !c.Bool("abort-on-interrupt") {
+		return nil, errors.New("--cancel-method needs --abort-on-interrupt")
+	}
+	if output.template, err = loadTemplate(c.String("template"), c.String("template-file")); err != nil {
+		return nil, err
+	}
+	return &session{
+		schema:           schema,
+		client:           client,
+		coin:             coin,
+		wallet:           wallet,
+		user:             user,
+		timeout:          timeout,
+		output:           output,
+		recorder:         recorder,
+		verbose:          verbose,
+		dryRun:           c.Bool("dry-run"),
+		nextID:           c.Int("id"),
+		notify:           c.Bool("no-id"),
+		pageSize:         pageSize,
+		checkMethods:     c.Bool("check-methods") || c.Bool("strict") || c.Bool("refresh-methods"),
+		strict:           c.Bool("strict"),
+		refreshMethods:   c.Bool("refresh-methods"),
+		noExpand:         c.Bool("no-expand"),
+		concurrency:      c.Int("concurrency"),
+		stopOnError:      c.Bool("stop-on-error"),
+		keepGoing:        c.Bool("keep-going"),
+		callTimeout:      time.Duration(c.Int("call-timeout")) * time.Second,
+		mock:             mockFile != "",
+		failOnEmpty:      c.Bool("fail-on-empty"),
+		assertions:       assertions,
+		cache:            newResponseCache(c.Duration("cache"), cacheMethods(cfg)),
+		onResult:         c.String("on-result"),
+		hookStrict:       c.Bool("hook-strict"),
+		noWallet:         c.Bool("no-wallet"),
+		confirmation:     newConfirmation(c, cfg),
+		credentialSets:   cfg.Credentials,
+		aliases:          cfg.Aliases,
+		shims:            cfg.Shims,
+		unlockMethod:     c.String("unlock-method"),
+		unlockPassword:   c.String("unlock-password"),
+		unlockPasswords:  cfg.UnlockPasswords,
+		prom:             c.Bool("prom"),
+		metricName:       c.String("metric-name"),
+		abortOnInterrupt: c.Bool("abort-on-interrupt"),
+		cancelMethod:     c.String("cancel-method"),
+		cancelMethods:    cfg.CancelMethods,
+	}, nil
+}
+
+// makesManyCalls reports whether run makes more than one call, so connections are worth keeping open
+func makesManyCalls(c *cli.Context) bool {
+	if c.String("batch") != "" || c.String("script") != "" || c.Bool("repl") || c.Int("watch") > 0 || c.Int("repeat") > 0 ||
+		c.Bool("paginate") || c.String("coin") == allCoins || len(c.StringSlice("wallets")) > 0 {
+		return true
+	}
+	for _, arg := range c.Args() {
+		if arg == chainSeparator {
+			return true
+		}
+	}
+	return false
+}
+
+// withCredentials returns copy of session authenticating with credentials of single call:
+// named credential set from config file, or user and password, where missing user is the session one.
+// Session itself is returned if none are given
+func (s *session) withCredentials(set, user, password string) (*session, error) {
+	if set == "" && user == "" && password == "" {
+		return s, nil
+	}
+	token := ""
+	if set != "" {
+		if user != "" || password != "" {
+			return nil, errors.New("credentials can't be combined with user and password")
+		}
+		creds, ok := s.credentialSets[set]
+		if !ok {
+			names := make([]string, 0, len(s.credentialSets))
+			for name := range s.credentialSets {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown credentials %q; config file defines: %s", set, strings.Join(names, ", "))
+		}
+		user, password, token = creds.User, creds.Password, creds.Token
+	} else if user == "" {
+		user = s.user
+	}
+	cs := *s
+	cs.client = s.client.WithAuth(user, password, token)
+	cs.user = user
+	return &cs, nil
+}
+
+// credentials are wallet and authentication settings resolved for coin
+type credentials struct {
+	wallet   string
+	user     string
+	password string
+	token    string
+}
+
+// resolveCredentials resolves credentials for coin from flags, secret files and config file
+func resolveCredentials(c *cli.Context, coin string, cfg *config) (credentials, error) {
+	// load flags
+	wallet := c.String("wallet")
+	user := c.String("user")
+	password := c.String("password")
+	token := c.String("token")
+	// secrets from files override inline values
+	if path := c.String("password-file"); path != "" {
+		var err error
+		if password, err = readSecretFile(path); err != nil {
+			return credentials{}, err
+		}
+	}
+	if path := c.String("token-file"); path != "" {
+		var err error
+		if token, err = readSecretFile(path); err != nil {
+			return credentials{}, err
+		}
+	}
+	if token != "" && (c.IsSet("password") || c.IsSet("password-file")) {
+		return credentials{}, errors.New("--token and --password can't be used together")
+	}
+	// wallet is resolved from --wallet, then BITCART_WALLET, then coin wallet from config file,
+	// the same config < env < flags precedence as other settings.
+	// --no-wallet drops it, so wallet-agnostic methods don't make daemon load a wallet
+	// --wallet-path names file-backed wallet by its absolute path instead
+	if c.IsSet("wallet-path") {
+		for _, name := range []string{"wallet", "wallets", "no-wallet"} {
+			if c.IsSet(name) {
+				return credentials{}, fmt.Errorf("--wallet-path can't be combined with --%s", name)
+			}
+		}
+		var err error
+		if wallet, err = resolveWalletPath(c.String("wallet-path")); err != nil {
+			return credentials{}, err
+		}
+	} else if c.Bool("no-wallet") {
+		if c.IsSet("wallet") || len(c.StringSlice("wallets")) > 0 {
+			return credentials{}, errors.New("--no-wallet can't be combined with --wallet or --wallets")
+		}
+		wallet = ""
+	} else if !c.IsSet("wallet") {
+		wallet = cfg.Coins[coin].Wallet
+		if envWallet := os.Getenv("BITCART_WALLET"); envWallet != "" {
+			wallet = envWallet
+		}
+	}
+	// explicit flags override values from config file
+	if coinCfg, ok := cfg.Coins[coin]; ok {
+		if coinCfg.User != "" && !c.IsSet("user") {
+			user = coinCfg.User
+		}
+		if coinCfg.Password != "" && !c.IsSet("password") && !c.IsSet("password-file") {
+			password = coinCfg.Password
+		}
+	}
+	return credentials{wallet: wallet, user: user, password: password, token: token}, nil
+}
+
+// resolveWalletPath returns absolute path of wallet file, with leading ~ expanded to home directory
+func resolveWalletPath(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("--wallet-path is empty")
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("expanding --wallet-path: %v", err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	return filepath.Abs(path)
+}
+
+// resolveRPCVersion returns JSON-RPC version for coin, --rpc-version overrides config file
+func resolveRPCVersion(c *cli.Context, coin string, cfg *config) string {
+	if version := cfg.Coins[coin].RPCVersion; version != "" && !c.IsSet("rpc-version") {
+		return version
+	}
+	return c.String("rpc-version")
+}
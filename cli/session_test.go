@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+// testFlags are global flags read by credential resolution and config export
+var testFlags = []cli.Flag{
+	cli.StringFlag{Name: "wallet"},
+	cli.StringFlag{Name: "wallet-path"},
+	cli.BoolFlag{Name: "no-wallet"},
+	cli.StringSliceFlag{Name: "wallets"},
+	cli.StringFlag{Name: "user", Value: "electrum"},
+	cli.StringFlag{Name: "password", Value: "electrumz"},
+	cli.StringFlag{Name: "password-file"},
+	cli.StringFlag{Name: "token"},
+	cli.StringFlag{Name: "token-file"},
+	cli.IntFlag{Name: "timeout", Value: 30},
+	cli.StringFlag{Name: "proxy"},
+	cli.StringFlag{Name: "rpc-version", Value: "2.0"},
+}
+
+// testContext returns context of app with testFlags parsed from args, like the one actions get
+func testContext(t *testing.T, args ...string) *cli.Context {
+	t.Helper()
+	var ctx *cli.Context
+	app := cli.NewApp()
+	app.Flags = testFlags
+	app.Action = func(c *cli.Context) error {
+		ctx = c
+		return nil
+	}
+	if err := app.Run(append([]string{"bitcart-cli"}, args...)); err != nil {
+		t.Fatal(err)
+	}
+	return ctx
+}
+
+func TestResolveCredentials(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := ioutil.WriteFile(passwordFile, []byte("filepass\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config{Coins: map[string]coinConfig{"btc": {User: "cfguser", Password: "cfgpass", Wallet: "cfgwallet"}}}
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		want credentials
+	}{
+		{"config file", "", nil, credentials{wallet: "cfgwallet", user: "cfguser", password: "cfgpass"}},
+		{"env wallet", "envwallet", nil, credentials{wallet: "envwallet", user: "cfguser", password: "cfgpass"}},
+		{"flags", "envwallet", []string{"--wallet", "flagwallet", "--user", "u", "--password", "p"}, credentials{wallet: "flagwallet", user: "u", password: "p"}},
+		{"password file", "", []string{"--password-file", passwordFile}, credentials{wallet: "cfgwallet", user: "cfguser", password: "filepass"}},
+		{"no wallet", "envwallet", []string{"--no-wallet"}, credentials{user: "cfguser", password: "cfgpass"}},
+	}
+	for _, tt := range tests {
+		os.Setenv("BITCART_WALLET", tt.env)
+		got, err := resolveCredentials(testContext(t, tt.args...), "btc", cfg)
+		if err != nil {
+			t.Errorf("%s: resolveCredentials() error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: resolveCredentials() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+	os.Unsetenv("BITCART_WALLET")
+	// coins without config use flag defaults
+	got, err := resolveCredentials(testContext(t), "ltc", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (credentials{user: "electrum", password: "electrumz"}); got != want {
+		t.Errorf("resolveCredentials() of unconfigured coin = %+v, want %+v", got, want)
+	}
+}
+
+func TestResolveCredentialsConflicts(t *testing.T) {
+	tests := [][]string{
+		{"--token", "t", "--password", "p"},
+		{"--wallet-path", "/tmp/w", "--wallet", "w"},
+		{"--no-wallet", "--wallet", "w"},
+	}
+	for _, args := range tests {
+		if _, err := resolveCredentials(testContext(t, args...), "btc", &config{}); err == nil {
+			t.Errorf("resolveCredentials() with %q succeeded, want error", args)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+)
+
+// shimConfig maps friendly method invocation to daemon method, see bitcart.Shim
+type shimConfig struct {
+	Method   string                 `yaml:"method"`
+	Args     []string               `yaml:"args,omitempty"`
+	Params   interface{}            `yaml:"params,omitempty"`
+	Defaults map[string]interface{} `yaml:"defaults,omitempty"`
+}
+
+// applyShim returns daemon method and params of method invocation defined in shims,
+// other methods are returned unchanged
+func applyShim(shims map[string]shimConfig, method string, params interface{}) (string, interface{}, error) {
+	shimCfg, ok := shims[method]
+	if !ok {
+		return method, params, nil
+	}
+	if shimCfg.Method == "" {
+		return "", nil, fmt.Errorf("shim %s has no method", method)
+	}
+	shim := bitcart.Shim{Method: shimCfg.Method, Args: shimCfg.Args, Defaults: map[string]interface{}{}}
+	// YAML objects are decoded with non-string keys
+	var err error
+	if shimCfg.Params != nil {
+		if shim.Params, err = jsonValue(shimCfg.Params); err != nil {
+			return "", nil, err
+		}
+	}
+	for arg, value := range shimCfg.Defaults {
+		if shim.Defaults[arg], err = jsonValue(value); err != nil {
+			return "", nil, err
+		}
+	}
+	if params, err = shim.Apply(method, params); err != nil {
+		return "", nil, fmt.Errorf("shim %v", err)
+	}
+	return shim.Method, params, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// defaultSignatureHeader is header holding request signature unless --hmac-header is set
+const defaultSignatureHeader = "X-Signature"
+
+// hmacAlgorithms are hash functions supported by --hmac-algorithm
+var hmacAlgorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// signTransport is http transport which adds hex HMAC of request body under secret in header
+type signTransport struct {
+	transport http.RoundTripper
+	secret    []byte
+	header    string
+	hash      func() hash.Hash
+}
+
+// newSignTransport returns transport signing requests with algorithm, which must be one of hmacAlgorithms
+func newSignTransport(transport http.RoundTripper, secret, algorithm, header string) (*signTransport, error) {
+	hash, ok := hmacAlgorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported HMAC algorithm %q, expected sha1, sha256 or sha512", algorithm)
+	}
+	if header == "" {
+		header = defaultSignatureHeader
+	}
+	return &signTransport{transport: transport, secret: []byte(secret), header: header, hash: hash}, nil
+}
+
+func (t *signTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var data []byte
+	if req.Body != nil {
+		var err error
+		data, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	mac := hmac.New(t.hash, t.secret)
+	mac.Write(data)
+	req = req.Clone(req.Context())
+	req.Header.Set(t.header, hex.EncodeToString(mac.Sum(nil)))
+	if req.Body != nil {
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}
+	}
+	return t.transport.RoundTrip(req)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// shutdown is cancelled on SIGINT or SIGTERM, aborting requests in flight, as calls are bound to it
+var shutdown = context.Background()
+
+// handleSignals cancels shutdown context on the first signal, the second one exits immediately
+func handleSignals() {
+	ctx, cancel := context.WithCancel(context.Background())
+	shutdown = ctx
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+		<-signals
+		os.Exit(exitInterrupted)
+	}()
+}
+
+// interrupted reports whether shutdown was requested
+func interrupted() bool {
+	return shutdown.Err() != nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli"
+)
+
+// stdinRequest is a complete invocation read by --stdin-request,
+// non-empty fields override the corresponding flags
+type stdinRequest struct {
+	Coin   string      `json:"coin"`
+	Wallet string      `json:"wallet"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+}
+
+// readStdinRequest decodes single request object from r
+func readStdinRequest(r io.Reader) (*stdinRequest, error) {
+	var request stdinRequest
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		return nil, fmt.Errorf("parsing request from stdin: %v", err)
+	}
+	if request.Method == "" {
+		return nil, errors.New("parsing request from stdin: method is required")
+	}
+	switch request.Params.(type) {
+	case nil:
+		request.Params = []interface{}{}
+	case []interface{}, map[string]interface{}:
+	default:
+		return nil, errors.New("parsing request from stdin: params must be array or object")
+	}
+	return &request, nil
+}
+
+// runStdinRequest makes the call read from stdin
+func runStdinRequest(c *cli.Context, coins map[string]string, cfg *config) error {
+	request, err := readStdinRequest(os.Stdin)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	coin := c.String("coin")
+	if request.Coin != "" {
+		coin = resolveCoin(request.Coin, coins)
+	}
+	s, err := newSession(c, coin, coins, cfg)
+	if err != nil {
+		return fail(err, exitFailure)
+	}
+	if request.Wallet != "" {
+		s.wallet = request.Wallet
+	}
+	method, params, err := s.prepareCall(request.Method, request.Params, askStdin)
+	if err != nil {
+		return err
+	}
+	return s.call(method, params)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+)
+
+// tableRows returns value as list of flat objects, ok is false if it isn't tabular
+func tableRows(value interface{}) ([]map[string]interface{}, bool) {
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, false
+	}
+	rows := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		row, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		for _, field := range row {
+			switch field.(type) {
+			case map[string]interface{}, []interface{}:
+				return nil, false
+			}
+		}
+		rows[i] = row
+	}
+	return rows, true
+}
+
+// formatCell converts scalar JSON value to table cell text
+func formatCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// tableColumns returns sorted union of object keys
+func tableColumns(rows []map[string]interface{}) []string {
+	columnSet := map[string]bool{}
+	for _, row := range rows {
+		for key := range row {
+			columnSet[key] = true
+		}
+	}
+	columns := make([]string, 0, len(columnSet))
+	for key := range columnSet {
+		columns = append(columns, key)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// renderTable renders list of flat objects as aligned text table,
+// with columns from union of object keys
+func renderTable(rows []map[string]interface{}) []byte {
+	columns := tableColumns(rows)
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = strings.ToUpper(column)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = formatCell(row[column])
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	w.Flush()
+	return bytes.TrimRight(buf.Bytes(), "\n")
+}
+
+// renderCSV renders list of objects as CSV with header of union of object keys.
+// Nested values are JSON-encoded in their cells
+func renderCSV(value interface{}) ([]byte, error) {
+	// empty path only decodes raw JSON
+	value, err := selectPath(value, "")
+	if err != nil {
+		return nil, err
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, errors.New("result is not a list of objects, use --table or default JSON output")
+	}
+	rows := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		if rows[i], ok = item.(map[string]interface{}); !ok {
+			return nil, errors.New("result is not a list of objects, use --table or default JSON output")
+		}
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	columns := tableColumns(rows)
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(columns)
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			switch field := row[column].(type) {
+			case map[string]interface{}, []interface{}:
+				b, err := bitcart.Marshal(field, true)
+				if err != nil {
+					return nil, err
+				}
+				cells[i] = string(b)
+			default:
+				cells[i] = formatCell(field)
+			}
+		}
+		w.Write(cells)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\r\n"), nil
+}
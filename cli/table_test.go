@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRenderCSV(t *testing.T) {
+	value := []interface{}{
+		map[string]interface{}{"txid": "aa", "amount": json.Number("1.5"), "label": "coffee, tea"},
+		map[string]interface{}{"txid": "bb", "inputs": []interface{}{"x"}, "note": nil},
+	}
+	got, err := renderCSV(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "amount,inputs,label,note,txid\n" +
+		`1.5,,"coffee, tea",,aa` + "\n" +
+		`,"[""x""]",,,bb`
+	if string(got) != want {
+		t.Errorf("renderCSV() = %q, want %q", got, want)
+	}
+	if got, err := renderCSV([]interface{}{}); err != nil || got != nil {
+		t.Errorf("renderCSV() of empty list = %q, %v, want nothing", got, err)
+	}
+	for _, value := range []interface{}{map[string]interface{}{}, []interface{}{1.0}} {
+		if _, err := renderCSV(value); err == nil {
+			t.Errorf("renderCSV(%v) succeeded, want error", value)
+		}
+	}
+}
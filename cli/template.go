@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"text/template"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+)
+
+// templateFuncs are available in --template in addition to text/template builtins
+var templateFuncs = template.FuncMap{
+	// json encodes value as compact JSON, e.g. {{ json .outputs }}
+	"json": func(value interface{}) (string, error) {
+		b, err := bitcart.Marshal(value, true)
+		return string(b), err
+	},
+}
+
+// loadTemplate parses --template text or --template-file, nil is returned if neither is set
+func loadTemplate(text, file string) (*template.Template, error) {
+	name := "--template"
+	if file != "" {
+		if text != "" {
+			return nil, errors.New("--template and --template-file can't be combined")
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		text, name = string(data), file
+	}
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// renderTemplate executes tmpl with decoded value, trailing newline is removed
+func renderTemplate(tmpl *template.Template, value interface{}) ([]byte, error) {
+	if raw, ok := value.(json.RawMessage); ok {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, value); err != nil {
+		return nil, fmt.Errorf("executing template: %v", err)
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
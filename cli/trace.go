@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+)
+
+// callTrace records requests and responses of the run for --trace-file, nil unless it is set
+var callTrace *trace
+
+// trace collects HTTP exchanges, written to file as JSON array when the run ends
+type trace struct {
+	path    string
+	mu      sync.Mutex
+	entries []traceEntry
+}
+
+// traceEntry is one request with its response, or error if there was none
+type traceEntry struct {
+	Started    string         `json:"started"`
+	DurationMS float64        `json:"duration_ms"`
+	Request    traceMessage   `json:"request"`
+	Response   *traceResponse `json:"response,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// traceMessage holds headers and body of request or response, secret headers are redacted.
+// JSON bodies are embedded as is, others as text
+type traceMessage struct {
+	Method  string            `json:"method,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers"`
+	Body    interface{}       `json:"body,omitempty"`
+}
+
+type traceResponse struct {
+	Status   string `json:"status"`
+	Protocol string `json:"protocol"`
+	traceMessage
+}
+
+// newTrace returns trace written to path, which is created right away so unwritable paths fail early
+func newTrace(path string) (*trace, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return &trace{path: path, entries: []traceEntry{}}, nil
+}
+
+// add records exchange
+func (t *trace) add(entry traceEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = append(t.entries, entry)
+}
+
+// write saves recorded exchanges to trace file, nil trace writes nothing
+func (t *trace) write() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(t.entries); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(t.path, buf.Bytes(), 0600)
+}
+
+// traceHeaders returns single-valued copy of headers with secret ones redacted
+func traceHeaders(header http.Header) map[string]string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	headers := make(map[string]string, len(names))
+	for _, name := range names {
+		headers[name] = header.Get(name)
+		if bitcart.IsSecretHeader(name) {
+			headers[name] = "<redacted>"
+		}
+	}
+	return headers
+}
+
+// traceBody returns body embedded in trace
+func traceBody(body []byte) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return json.RawMessage(body)
+	}
+	return string(body)
+}
+
+// traceTransport is http transport recording exchanges in trace.
+// With statusOnly response bodies are not recorded, so streamed responses aren't held in memory
+type traceTransport struct {
+	transport  http.RoundTripper
+	trace      *trace
+	statusOnly bool
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := traceEntry{
+		Started: time.Now().Format(time.RFC3339Nano),
+		Request: traceMessage{Method: req.Method, URL: req.URL.String(), Headers: traceHeaders(req.Header)},
+	}
+	if hasSecretBody(req) {
+		entry.Request.Body = "<redacted>"
+	} else if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := ioutil.ReadAll(body)
+			entry.Request.Body = traceBody(data)
+		}
+	}
+	start := time.Now()
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		entry.DurationMS = milliseconds(time.Since(start))
+		entry.Error = err.Error()
+		t.trace.add(entry)
+		return nil, err
+	}
+	entry.Response = &traceResponse{
+		Status:       resp.Status,
+		Protocol:     resp.Proto,
+		traceMessage: traceMessage{Headers: traceHeaders(resp.Header)},
+	}
+	if hasSecretBody(req) {
+		entry.Response.Body = "<redacted>"
+	} else if !t.statusOnly {
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		entry.Response.Body = traceBody(body)
+	}
+	entry.DurationMS = milliseconds(time.Since(start))
+	t.trace.add(entry)
+	return resp, nil
+}
+
+// milliseconds returns duration in fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// writeTrace saves trace when the run ends, failure to write it is only reported
+func writeTrace() {
+	if err := callTrace.write(); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: writing trace:", err)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MrNaif2018/bitcart/cli/bitcart"
+	"github.com/MrNaif2018/jsonrpc"
+)
+
+// defaultUnlockMethod is method called with wallet password to unlock wallet unless --unlock-method is set
+const defaultUnlockMethod = "unlock"
+
+// secretBodyKey marks context of requests whose body holds secrets
+type secretBodyKey struct{}
+
+// withSecretBody returns ctx marking requests sent with it as holding secrets,
+// their bodies and bodies of their responses are left out of verbose output and traces
+func withSecretBody(ctx context.Context) context.Context {
+	return context.WithValue(ctx, secretBodyKey{}, true)
+}
+
+// hasSecretBody reports whether request body holds secrets, see withSecretBody
+func hasSecretBody(req *http.Request) bool {
+	secret, _ := req.Context().Value(secretBodyKey{}).(bool)
+	return secret
+}
+
+// walletPassword returns password unlocking session wallet: --unlock-password, or the one
+// of the wallet in unlock_passwords section of config file
+func (s *session) walletPassword() string {
+	if s.unlockPassword != "" {
+		return s.unlockPassword
+	}
+	return s.unlockPasswords[s.wallet]
+}
+
+// unlockWallet unlocks session wallet after method failed with rpcErr, reporting whether
+// the call should be retried. Failure to unlock is only reported, the original error stays
+func (s *session) unlockWallet(method string, rpcErr *jsonrpc.RPCError) bool {
+	password := s.walletPassword()
+	if password == "" || method == s.unlockMethod || !bitcart.IsWalletLocked(rpcErr) {
+		return false
+	}
+	if s.verbose > 0 {
+		fmt.Fprintf(stderr, "* wallet is locked, calling %s\n", s.unlockMethod)
+	}
+	if err := s.unlock(password); err != nil {
+		fmt.Fprintln(stderr, "Warning: unlocking wallet:", err)
+		return false
+	}
+	if s.verbose > 0 {
+		fmt.Fprintf(stderr, "* wallet unlocked, retrying %s\n", method)
+	}
+	return true
+}
+
+// unlock calls unlock method with password as its only param. The request is never printed or traced
+func (s *session) unlock(password string) error {
+	// errors of the call being unlocked keep its id
+	defer func(id int) { s.lastID = id }(s.lastID)
+	request := s.newRequest(s.unlockMethod, password)
+	ctx, cancel := s.callContext()
+	defer cancel()
+	start := time.Now()
+	result, err := s.client.SendContext(withSecretBody(ctx), request)
+	s.logCall(request, time.Since(start), result, err)
+	if err != nil {
+		return errors.New(s.callError(err))
+	}
+	if result.Error != nil {
+		return fmt.Errorf("%s failed: %s", s.unlockMethod, result.Error.Message)
+	}
+	return nil
+}
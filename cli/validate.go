@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/urfave/cli"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// loadSchema compiles JSON Schema file, relative $ref paths are resolved against its directory
+func loadSchema(path string) (*gojsonschema.Schema, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewReferenceLoader("file://" + filepath.ToSlash(abs)))
+	if err != nil {
+		return nil, fmt.Errorf("loading schema %s: %v", path, err)
+	}
+	return schema, nil
+}
+
+// validateResult checks result against session schema, printing each violation
+func (s *session) validateResult(result interface{}) error {
+	if s.schema == nil {
+		return nil
+	}
+	validation, err := s.schema.Validate(gojsonschema.NewGoLoader(result))
+	if err != nil {
+		return fail(fmt.Sprintf("validating result: %v", err), exitFailure)
+	}
+	if validation.Valid() {
+		return nil
+	}
+	violations := make([]string, len(validation.Errors()))
+	for i, violation := range validation.Errors() {
+		violations[i] = violation.String()
+	}
+	if errorJSON {
+		reportError(errorInfo{Type: "validation", Message: "result doesn't match schema", Data: violations})
+		return cli.NewExitError("", exitInvalidResult)
+	}
+	for _, violation := range violations {
+		fmt.Fprintln(stderr, "-", violation)
+	}
+	return fail("result doesn't match schema", exitInvalidResult)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// daemonVersion returns version reported by daemon
+func (s *session) daemonVersion() (string, error) {
+	result, err := s.do(pingMethod)
+	if err != nil {
+		return "", errors.New(s.callError(err))
+	}
+	if result.Error != nil {
+		return "", errors.New(result.Error.Message)
+	}
+	return versionString(result.Result)
+}
+
+// versionString returns version result as text, results which aren't strings are printed as JSON
+func versionString(result interface{}) (string, error) {
+	if version, ok := result.(string); ok {
+		return version, nil
+	}
+	b, err := json.Marshal(result)
+	return string(b), err
+}
+
+// printVersions prints CLI version and version of each coin's daemon which is reachable
+func printVersions(version string, coins []string, newCoinSession func(coin string) (*session, error)) error {
+	fmt.Printf("bitcart-cli\t%s\n", version)
+	for _, coin := range coins {
+		s, err := newCoinSession(coin)
+		if err == nil {
+			var daemonVersion string
+			if daemonVersion, err = s.daemonVersion(); err == nil {
+				fmt.Printf("%s\t%s\n", coin, daemonVersion)
+				continue
+			}
+		}
+		fmt.Printf("%s\tunavailable: %v\n", coin, err)
+	}
+	return nil
+}
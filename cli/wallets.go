@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/urfave/cli"
+)
+
+// listWalletsMethod is daemon method returning loaded wallets
+const listWalletsMethod = "list_wallets"
+
+// listWallets returns names of wallets loaded by daemon
+func (s *session) listWallets() ([]string, error) {
+	result, err := s.do(listWalletsMethod)
+	if err != nil {
+		return nil, errors.New(s.callError(err))
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("daemon doesn't support listing wallets (%s): %s", listWalletsMethod, result.Error.Message)
+	}
+	entries, ok := result.Result.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected %s result: %v", listWalletsMethod, result.Result)
+	}
+	wallets := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		wallets = append(wallets, walletName(entry))
+	}
+	return wallets, nil
+}
+
+// walletName returns name of wallet list entry, which is either a name or object with wallet file path
+func walletName(entry interface{}) string {
+	switch entry := entry.(type) {
+	case string:
+		return entry
+	case map[string]interface{}:
+		if path, ok := entry["path"].(string); ok {
+			return filepath.Base(path)
+		}
+	}
+	b, _ := json.Marshal(entry)
+	return string(b)
+}
+
+// printWallets prints wallets of each coin, one per line, prefixed with coin name when there are several coins.
+// With asJSON a list is printed for single coin and an object keyed by coin otherwise
+func printWallets(coins []string, newCoinSession func(coin string) (*session, error), asJSON bool) error {
+	failed := false
+	results := make(map[string]interface{}, len(coins))
+	for _, coin := range coins {
+		s, err := newCoinSession(coin)
+		var wallets []string
+		if err == nil {
+			wallets, err = s.listWallets()
+		}
+		if err != nil {
+			failed = true
+			if len(coins) == 1 {
+				return fail(err, exitFailure)
+			}
+			if asJSON {
+				results[coin] = map[string]string{"error": err.Error()}
+			} else {
+				fmt.Printf("%s\tunavailable: %v\n", coin, err)
+			}
+			continue
+		}
+		results[coin] = wallets
+		if asJSON {
+			continue
+		}
+		for _, wallet := range wallets {
+			if len(coins) > 1 {
+				fmt.Printf("%s\t%s\n", coin, wallet)
+			} else {
+				fmt.Println(wallet)
+			}
+		}
+	}
+	if asJSON {
+		var value interface{} = results
+		if len(coins) == 1 {
+			value = results[coins[0]]
+		}
+		b, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return fail(err, exitFailure)
+		}
+		fmt.Println(string(b))
+	}
+	if failed {
+		return cli.NewExitError("", exitFailure)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// clearScreen is ANSI sequence moving cursor home and clearing terminal
+const clearScreen = "\033[H\033[2J"
+
+// watch repeats the call every interval until interrupted, errors don't stop it
+func (s *session) watch(interval time.Duration, method string, params interface{}) error {
+	return repeat(interval, method, s.output.file == "", func() {
+		s.call(method, params)
+	})
+}
+
+// repeat runs fn every interval until interrupted, under a header with title and time.
+// Screen is cleared before each run if clear is set
+func repeat(interval time.Duration, title string, clear bool, fn func()) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if clear {
+			fmt.Print(clearScreen)
+		}
+		fmt.Printf("Every %v: %s\t%s\n\n", interval, title, time.Now().Format(time.RFC1123))
+		fn()
+		select {
+		case <-shutdown.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}